@@ -6,7 +6,9 @@ import (
 	"path/filepath"
 	"slices"
 	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/tdewolff/minify/v2"
 )
@@ -25,9 +27,32 @@ type asset struct {
 
 	dynamicContent []func() []byte // Dynamic content providers
 
+	// transform and the dependents are wired once by NewAssetMin and never change.
+	//
+	// transform, when set, is called before every regeneration and returns the
+	// step applied to the written (not yet minified) content. It runs WITHOUT
+	// h.mu held, so it may read other handlers; the step it returns runs under h.mu.
+	transform func() func([]byte) []byte
+	// dependents are invalidated whenever this asset's output changes (e.g. the
+	// index HTML when its critical CSS is derived from the stylesheet).
+	dependents []*asset
+	// sourceDependents are invalidated only when this asset's written content
+	// (before transform) changes: the stylesheet is pruned against the HTML
+	// sources, so the critical CSS inlined into the HTML output must not
+	// rebuild it again.
+	sourceDependents []*asset
+	written          []byte // written content of the last build, kept for sourceDependents
+	// sourceMap makes every build also produce a v3 source map (cachedMap);
+	// sourceMapURL, when set, is the reference appended to the output.
+	sourceMap    bool
+	sourceMapURL string
+	cachedMap    []byte
+
 	mu             sync.RWMutex // Mutex for thread-safe access to the cache
 	cachedMinified []byte       // Minified content ready to serve
+	modTime        time.Time    // When cachedMinified last changed
 	cacheValid     bool         // True if cache matches current content
+	held           bool         // cachedMinified comes from a previous flush and is served until release (see warmStart)
 }
 
 // ContentFile represents a file with its path and content
@@ -90,8 +115,6 @@ func (h *asset) UpdateContentInSlot(filePath, event string, f *ContentFile, slot
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
-	h.cacheValid = false // direct field access under lock instead of calling InvalidateCache which locks again
-
 	var filesToUpdate *[]*ContentFile
 	switch slot {
 	case "open":
@@ -104,49 +127,59 @@ func (h *asset) UpdateContentInSlot(filePath, event string, f *ContentFile, slot
 
 	switch event {
 	case "create", "write", "modify":
+		h.cacheValid = false // direct field access under lock instead of calling InvalidateCache which locks again
 
 		if idx := findFileIndex(*filesToUpdate, filePath); idx != -1 {
 			// Exact path exists: replace content
 			(*filesToUpdate)[idx] = f
 		} else {
-			// File with this path not found. This can happen in a rename flow where
-			// a rename event is sent for the old file and a create event for the
-			// new file arrives afterwards. Instead of blindly appending and
-			// creating a duplicate, try to detect if this new file corresponds
-			// to an existing memory entry (rename case) by comparing content.
-			replaced := false
-			for i, existing := range *filesToUpdate {
-				if bytes.Equal(existing.Content, f.Content) {
-					// Reuse existing entry: update its path and content
-					(*filesToUpdate)[i].Path = filePath
-					(*filesToUpdate)[i].Content = f.Content
-					replaced = true
-					break
-				}
-			}
-			if !replaced {
-				// No match found: insert at the Path-sorted position so a slot's order
-				// depends only on the set of modules present, never on the order in
-				// which their registration events arrived (ScheduleSSRLoad's background
-				// scan and a watcher-driven reload can race on process boot).
-				idx := sort.Search(len(*filesToUpdate), func(i int) bool {
-					return (*filesToUpdate)[i].Path >= filePath
-				})
-				*filesToUpdate = append(*filesToUpdate, nil)
-				copy((*filesToUpdate)[idx+1:], (*filesToUpdate)[idx:])
-				(*filesToUpdate)[idx] = f
-			}
+			// Insert at the Path-sorted position so a slot's order depends only
+			// on the set of modules present, never on the order in which their
+			// registration events arrived (ScheduleSSRLoad's background scan and
+			// a watcher-driven reload can race on process boot).
+			idx := sort.Search(len(*filesToUpdate), func(i int) bool {
+				return (*filesToUpdate)[i].Path >= filePath
+			})
+			*filesToUpdate = append(*filesToUpdate, nil)
+			copy((*filesToUpdate)[idx+1:], (*filesToUpdate)[idx:])
+			(*filesToUpdate)[idx] = f
 		}
-	case "rename":
-	case "remove", "delete":
+	case "rename", "remove", "delete":
+		// A rename event names the old path: the new one arrives as a create
+		// (or through NewFileRenameEvent), so the old entry goes away.
+		// Removing an absent entry changes nothing and keeps the cache.
 		if idx := findFileIndex(*filesToUpdate, filePath); idx != -1 {
 			*filesToUpdate = slices.Delete((*filesToUpdate), idx, idx+1)
+			h.cacheValid = false
 		}
 	}
 
 	return
 }
 
+// removeUnder drops every entry whose path is dir or lies below it, in every
+// slot. It reports whether any was dropped.
+func (h *asset) removeUnder(dir string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	removed := false
+	for _, files := range []*[]*ContentFile{&h.contentOpen, &h.contentMiddle, &h.contentClose} {
+		n := len(*files)
+		*files = slices.DeleteFunc(*files, func(f *ContentFile) bool { return pathUnder(f.Path, dir) })
+		removed = removed || len(*files) != n
+	}
+	if removed {
+		h.cacheValid = false
+	}
+	return removed
+}
+
+// pathUnder reports whether path is dir or lies below it.
+func pathUnder(path, dir string) bool {
+	path, dir = filepath.Clean(path), filepath.Clean(dir)
+	return path == dir || strings.HasPrefix(path, dir+string(filepath.Separator))
+}
+
 func findFileIndex(files []*ContentFile, filePath string) int {
 	for i, f := range files {
 		if f.Path == filePath {
@@ -156,37 +189,54 @@ func findFileIndex(files []*ContentFile, filePath string) int {
 	return -1
 }
 
-// WriteContent processes the asset content and writes it to the provided buffer
-func (h *asset) WriteContent(buf *bytes.Buffer) {
+// segment is one contributor to an asset's output, in write order.
+type segment struct {
+	slot    string // "init", "open", "dynamic", "middle" or "close"
+	source  string // ContentFile.Path; "" for init code and dynamic content
+	content []byte
+	newline bool // followed by "\n" in the bundle
+}
+
+// segments lists the pieces WriteContent concatenates, in order.
+// It assumes the caller holds h.mu.
+func (h *asset) segments() []segment {
+	var out []segment
 	if h.initCode != nil {
 		initCode, err := h.initCode()
 		if err == nil {
-			buf.WriteString(initCode)
+			out = append(out, segment{slot: "init", content: []byte(initCode)})
 		}
 	}
 
-	// Write open content first
+	// Open content first
 	for _, f := range h.contentOpen {
-		buf.Write(f.Content)
-		buf.WriteString("\n") // Add newline between files
+		out = append(out, segment{slot: "open", source: f.Path, content: f.Content, newline: true})
 	}
 
-	// Write dynamic content
+	// Dynamic content
 	for _, fn := range h.dynamicContent {
-		buf.Write(fn())
-		buf.WriteString("\n")
+		out = append(out, segment{slot: "dynamic", content: fn(), newline: true})
 	}
 
-	// Then write middle content files
+	// Then middle content files
 	for _, f := range h.contentMiddle {
-		buf.Write(f.Content)
-		buf.WriteString("\n") // Add newline between files
+		out = append(out, segment{slot: "middle", source: f.Path, content: f.Content, newline: true})
 	}
 
-	// Then write close content files
+	// Then close content files
 	for _, f := range h.contentClose {
-		buf.Write(f.Content)
-		buf.WriteString("\n") // Add newline between files
+		out = append(out, segment{slot: "close", source: f.Path, content: f.Content, newline: true})
+	}
+	return out
+}
+
+// WriteContent processes the asset content and writes it to the provided buffer
+func (h *asset) WriteContent(buf *bytes.Buffer) {
+	for _, s := range h.segments() {
+		buf.Write(s.content)
+		if s.newline {
+			buf.WriteString("\n") // Add newline between files
+		}
 	}
 }
 
@@ -201,25 +251,15 @@ func (h *asset) InvalidateCache() {
 // RegenerateCache generates the minified content for the asset and updates the cache.
 // It acquires a write lock to ensure thread-safe modification of the cache.
 func (h *asset) RegenerateCache(minifier *minify.M) error {
-	h.mu.Lock()
-	defer h.mu.Unlock()
-
-	var buf bytes.Buffer
-	h.WriteContent(&buf)
+	step := h.transformStep()
 
-	if minifier == nil {
-		h.cachedMinified = buf.Bytes()
-		h.cacheValid = true
-		return nil
-	}
-
-	minified, err := minifier.Bytes(h.mediatype, buf.Bytes())
+	h.mu.Lock()
+	changed, sourceChanged, err := h.build(minifier, step)
+	h.mu.Unlock()
 	if err != nil {
 		return err
 	}
-
-	h.cachedMinified = minified
-	h.cacheValid = true
+	h.invalidateDependents(changed, sourceChanged)
 	return nil
 }
 
@@ -241,32 +281,158 @@ func (h *asset) GetMinifiedContent(minifier *minify.M) ([]byte, error) {
 	}
 	h.mu.RUnlock()
 
+	step := h.transformStep()
+
 	// If the cache is invalid, acquire a write lock to regenerate it.
 	h.mu.Lock()
-	defer h.mu.Unlock()
 	// It's possible another goroutine regenerated the cache while we were waiting for the write lock.
 	// So, we need to double-check if the cache is still invalid.
 	if h.cacheValid {
+		defer h.mu.Unlock()
 		return h.cachedMinified, nil
 	}
+	changed, sourceChanged, err := h.build(minifier, step)
+	out := h.cachedMinified
+	h.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	h.invalidateDependents(changed, sourceChanged)
+	return out, nil
+}
+
+// transformStep resolves the post-processing step for the next build. It must
+// be called without h.mu held.
+func (h *asset) transformStep() func([]byte) []byte {
+	if h.transform == nil {
+		return nil
+	}
+	return h.transform()
+}
+
+// build writes, post-processes and minifies the content into the cache, and
+// with sourceMap builds its source map (see buildSourceMap).
+// It assumes the caller holds h.mu and reports whether the output and the
+// written content (see sourceDependents) changed.
+func (h *asset) build(minifier *minify.M, step func([]byte) []byte) (changed, sourceChanged bool, err error) {
+	if h.held {
+		return false, false, nil
+	}
 
 	var buf bytes.Buffer
 	h.WriteContent(&buf)
 
-	if minifier == nil {
-		h.cachedMinified = buf.Bytes()
-		h.cacheValid = true
-		return h.cachedMinified, nil
+	written := buf.Bytes()
+	if len(h.sourceDependents) > 0 && !bytes.Equal(written, h.written) {
+		h.written = bytes.Clone(written)
+		sourceChanged = true
 	}
-
-	minified, err := minifier.Bytes(h.mediatype, buf.Bytes())
+	out, err := h.process(written, minifier, step)
 	if err != nil {
-		return nil, err
+		return false, false, err
+	}
+
+	if h.sourceMap {
+		mapJSON, err := h.buildSourceMap(written, out, minifier, step)
+		if err != nil {
+			return false, false, err
+		}
+		h.cachedMap = mapJSON
+		if h.sourceMapURL != "" {
+			out = slices.Clip(out)
+			if len(out) > 0 && out[len(out)-1] != '\n' {
+				out = append(out, '\n')
+			}
+			out = append(out, sourceMappingComment(h.mediatype, h.sourceMapURL)...)
+		}
+	}
+
+	changed = !bytes.Equal(out, h.cachedMinified)
+	h.cachedMinified = out
+	h.cacheValid = true
+	if changed {
+		h.modTime = time.Now()
+	}
+	return changed, sourceChanged, nil
+}
+
+// process post-processes and minifies content, as build does with the
+// written content. The result may share memory with content.
+func (h *asset) process(content []byte, minifier *minify.M, step func([]byte) []byte) ([]byte, error) {
+	if step != nil {
+		content = step(content)
+	}
+	if minifier != nil {
+		return minifier.Bytes(h.mediatype, content)
+	}
+	return content, nil
+}
+
+// invalidateDependents marks the dependents stale after a build that changed
+// the output, and the sourceDependents after one that changed the written
+// content. Called without h.mu held so dependents never lock while this asset
+// is locked.
+func (h *asset) invalidateDependents(changed, sourceChanged bool) {
+	if changed {
+		for _, d := range h.dependents {
+			d.InvalidateCache()
+		}
+	}
+	if sourceChanged {
+		for _, d := range h.sourceDependents {
+			d.InvalidateCache()
+		}
 	}
+}
 
-	h.cachedMinified = minified
+// hold serves content, whatever the slots hold, until release.
+func (h *asset) hold(content []byte, modTime time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.cachedMinified = content
+	h.modTime = modTime
 	h.cacheValid = true
-	return h.cachedMinified, nil
+	h.held = true
+}
+
+// freeze keeps serving the current output until release, so a load can
+// mutate the slots without requests seeing a partial state. It reports
+// whether the asset was frozen: only a valid, not already held output is.
+func (h *asset) freeze() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.held || !h.cacheValid {
+		return false
+	}
+	h.held = true
+	return true
+}
+
+// release makes the next build use the slots again. It reports whether the
+// asset was held.
+func (h *asset) release() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.held {
+		return false
+	}
+	h.held = false
+	h.cacheValid = false
+	return true
+}
+
+// isHeld reports whether a warm-started output is still being served.
+func (h *asset) isHeld() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.held
+}
+
+// isCacheValid reports whether the cached output matches the current content.
+func (h *asset) isCacheValid() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.cacheValid
 }
 
 // URLPath returns the URL path for the asset.
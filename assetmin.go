@@ -1,11 +1,13 @@
 package assetmin
 
 import (
+	"context"
 	"os"
 	"path"
 	"path/filepath"
 	"regexp"
 	"sync"
+	"time"
 
 	"github.com/tdewolff/minify/v2"
 	"github.com/tdewolff/minify/v2/css"
@@ -27,13 +29,31 @@ type AssetMin struct {
 	faviconSvgHandler   *asset
 	indexHtmlHandler    *asset
 	min                 *minify.M
-	ssrEnabled          bool              // SSR branch activation flag
-	initialLoadFailed   bool              // true tras agotar los reintentos de ExtractAll; el próximo evento SSR debe reintentar el escaneo completo
-	diskMirrored        bool              // If true, assets are being mirrored to disk
-	allAssets           map[string]*asset // Keyed by outputPath - dedup
+	ssrEnabled          bool                     // SSR branch activation flag
+	initialLoadFailed   bool                     // true tras agotar los reintentos de ExtractAll; el próximo evento SSR debe reintentar el escaneo completo
+	modules             map[string]*ModuleStatus // SSR modules routed so far; see Modules
+	ssrAttempts         []LoadAttempt            // ExtractAll calls of the last (or current) SSR load
+	ssrLoadErr          error                    // error of the last completed SSR load; nil on success
+	diskMirrored        bool                     // If true, assets are being mirrored to disk
+	ownedFiles          map[string]bool          // files in OutputDir written by AssetMin; see ownedOutputs
+	outputMu            sync.Mutex               // guards outputHashes (taken without c.mu during flush writes)
+	outputHashes        map[string]string        // output path -> hash of the content last written or found there
+	allAssets           map[string]*asset        // Keyed by outputPath - dedup
 	log                 func(message ...any)
 	onSSRCompile        func() error
 	ssrLoading          sync.WaitGroup
+	writing             sync.WaitGroup  // flushes and event batches in progress; see track
+	ssrPending          int             // loads in progress; see Readiness
+	ssrDone             chan struct{}   // closed when ssrPending drops to zero
+	firstLoadDone       bool            // the first SSR load completed; later loads neither gate nor report loading
+	closeCtx            context.Context // cancelled by Close
+	closeAll            context.CancelFunc
+	closed              bool        // set by Close; events, loads and flushes are rejected
+	rescanTimer         *time.Timer // pending debounced full rescan; see scheduleRescan
+	rescanThen          func()      // runs after that rescan's load
+	eventsMu            sync.Mutex  // serializes applying event batches; taken before c.mu
+	pendingEvents       *eventBatch // events queued under EventDebounce
+	eventTimer          *time.Timer
 	minifyEnabled       bool
 	fromRoot            *rootCandidate
 	fromCss             *rootCandidate
@@ -42,6 +62,7 @@ type AssetMin struct {
 	imageProcessor      ImageProcessor
 	ssrExtractor        SSRExtractor
 	moduleSprites       map[string]*sprite.Sprite
+	iconFiles           map[string]string // .svg file path -> icon id added from it; see removeUnder and removeIconFile
 	spriteMu            sync.RWMutex
 	fontsMu             sync.RWMutex
 	fonts               font.Declaration // root module only; zero-value = none
@@ -53,11 +74,27 @@ type rootCandidate struct {
 }
 
 type Config struct {
-	OutputDir       string // eg: web/static, web/public, web/assets
-	RootDir         string // Root directory of the project where go.mod exists
-	AppName         string // Application name for templates (default: "MyApp")
-	AssetsURLPrefix    string                 // New: for HTTP routes
-	DevMode            bool                   // If true, disables caching (default: false)
+	OutputDir       string        // eg: web/static, web/public, web/assets
+	RootDir         string        // Root directory of the project where go.mod exists
+	AppName         string        // Application name for templates (default: "MyApp")
+	AssetsURLPrefix string        // New: for HTTP routes
+	DevMode         bool          // If true, disables caching (default: false)
+	CriticalCSS     bool          // If true, inlines the CSS rules used by the server-rendered HTML in <head> and loads style.css asynchronously
+	PruneCSS        bool          // If true (and not DevMode), drops style.css rules whose selectors are used by neither the HTML nor the JS bundle
+	CSSSafelist     []string      // Classes, ids or elements PruneCSS must always keep; a trailing "*" matches a prefix (eg: "btn-*")
+	SourceMaps      bool          // If true, builds style.css.map and script.js.map back to module origins (served in DevMode)
+	WriteSourceMaps bool          // If true (with SourceMaps), FlushToDisk also writes the .map files next to the bundles
+	SizeBudgets     []SizeBudget  // Minified size caps per asset and/or module; FlushToDisk fails when exceeded (logs in DevMode)
+	WriteSizeReport bool          // If true, FlushToDisk writes asset-sizes.json (see SizeReport) next to the assets
+	OutputSink      OutputSink    // Where outputs are written (default: NewDirSink(OutputDir)); eg: NewMemorySink() in tests
+	SSRRetry        RetryPolicy   // ExtractAll retries of LoadSSRModules (default: 5 attempts, 200ms doubling up to 3s)
+	WarmStart       bool          // If true, serves the outputs of a previous flush found in OutputSink until the first SSR load completes (without an extractor: the first file event)
+	LoadGate        LoadGate      // How asset routes answer while the first SSR load is in progress (default: LoadGateOff)
+	LoadGateTimeout time.Duration // LoadGateBlock: longest wait before answering 503 (default: 10s)
+	ModulesPath     string        // If set (and DevMode), RegisterRoutes serves Modules() as JSON there, eg: "/__assetmin/modules"
+	HealthPath      string        // If set, RegisterRoutes adds a public probe route there: 200 when ready, 503 while loading or failed
+	EventDebounce   time.Duration // If set, file events are queued and applied in one batch once none arrived for this long, eg: 50ms (see FlushEvents)
+	PlainAssetDirs  []string      // Directories (relative to RootDir) whose files stay plain assets in SSR mode, merged before the modules, eg: []string{"web/legacy"}
 }
 
 func NewAssetMin(ac *Config) *AssetMin {
@@ -73,6 +110,10 @@ func NewAssetMin(ac *Config) *AssetMin {
 	if c.AppName == "" {
 		c.AppName = "MyApp"
 	}
+	c.closeCtx, c.closeAll = context.WithCancel(context.Background())
+	if c.OutputSink == nil {
+		c.OutputSink = NewDirSink(c.OutputDir)
+	}
 
 	c.allAssets = make(map[string]*asset)
 
@@ -92,8 +133,18 @@ func NewAssetMin(ac *Config) *AssetMin {
 	c.mainJsHandler.urlPath = path.Join("/", ac.AssetsURLPrefix, jsMainFileName)
 	c.faviconSvgHandler.urlPath = path.Join("/", ac.AssetsURLPrefix, svgFaviconFileName)
 
-	c.indexHtmlHandler = NewHtmlHandler(ac, htmlMainFileName, c.mainStyleCssHandler.GetURLPath(), c.mainJsHandler.GetURLPath(), c.faviconSvgHandler.GetURLPath())
+	hh := newHtmlHandler(ac, htmlMainFileName, c.mainStyleCssHandler.GetURLPath(), c.mainJsHandler.GetURLPath(), c.faviconSvgHandler.GetURLPath())
+	c.indexHtmlHandler = hh.asset
 	c.indexHtmlHandler.urlPath = "/" // Index is always at root
+	if ac.CriticalCSS {
+		c.enableCriticalCSS(hh)
+	}
+	if ac.PruneCSS {
+		c.enablePruneCSS()
+	}
+	if ac.SourceMaps {
+		c.enableSourceMaps()
+	}
 	c.min.Add("text/html", &html.Minifier{
 		KeepDocumentTags: true,
 		KeepEndTags:      true,
@@ -139,6 +190,9 @@ func NewAssetMin(ac *Config) *AssetMin {
 		return []byte(twcss.FontFaces(d, prefix).String())
 	})
 
+	if ac.WarmStart {
+		c.warmStart()
+	}
 	return c
 }
 
@@ -174,6 +228,9 @@ func (c *AssetMin) EnsureOutputDirectoryExists() {
 func (c *AssetMin) refreshAsset(extension string) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
 
 	var handlers []*asset
 	switch extension {
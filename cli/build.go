@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/tinywasm/assetmin"
+)
+
+// runBuild loads the project once, writes every output to disk and prints a
+// summary. Any extraction, budget or write error exits with status 1.
+func runBuild(args []string, stdout, stderr io.Writer, opts Options) int {
+	p := projectFlags{newSSRExtractor: opts.NewSSRExtractor}
+	set := flag.NewFlagSet("assetmin build", flag.ContinueOnError)
+	set.SetOutput(stderr)
+	p.register(set)
+	embedDir := set.String("embed", "", "also generate a Go embed package of the outputs in this directory")
+	embedPkg := set.String("embed-pkg", "", "package name of the embed package (default: base name of -embed)")
+	if err := set.Parse(args); err != nil {
+		return 2
+	}
+	if set.NArg() > 0 {
+		fmt.Fprintf(stderr, "assetmin build: unexpected arguments %v\n", set.Args())
+		return 2
+	}
+
+	cfg, err := p.config()
+	if err != nil {
+		fmt.Fprintln(stderr, "assetmin build:", err)
+		return 1
+	}
+	am, err := p.load(cfg, func(message ...any) { fmt.Fprintln(stderr, message...) })
+	if err != nil {
+		fmt.Fprintln(stderr, "assetmin build:", err)
+		return 1
+	}
+	result, err := am.Flush()
+	if err != nil {
+		fmt.Fprintln(stderr, "assetmin build:", err)
+		return 1
+	}
+
+	m, err := assetmin.ReadManifest(cfg.OutputDir)
+	if err != nil {
+		fmt.Fprintln(stderr, "assetmin build:", err)
+		return 1
+	}
+	printSummary(stdout, cfg.OutputDir, m, result)
+
+	if *embedDir != "" {
+		if err := am.GenerateEmbedPackage(*embedDir, *embedPkg); err != nil {
+			fmt.Fprintln(stderr, "assetmin build:", err)
+			return 1
+		}
+		fmt.Fprintf(stdout, "embed package written to %s\n", *embedDir)
+	}
+	return 0
+}
+
+// printSummary writes one line per output: path, size and short hash, then
+// what the flush changed on disk.
+func printSummary(w io.Writer, outDir string, m *assetmin.Manifest, result *assetmin.FlushResult) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	var total int64
+	for _, e := range m.Assets {
+		fmt.Fprintf(tw, "%s\t%d B\t%s\t\n", e.Path, e.Size, e.Hash[:12])
+		total += e.Size
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "%d files, %d bytes in %s (%d written, %d unchanged, %d removed)\n",
+		len(m.Assets), total, outDir, len(result.Written), len(result.Unchanged), len(result.Removed))
+}
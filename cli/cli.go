@@ -0,0 +1,84 @@
+// Package cli is the assetmin command line: it bundles, minifies and serves
+// the web assets of a Go project without writing a Go program around the
+// library.
+//
+//	assetmin build -root . -out web/public
+//	assetmin serve -root . -addr localhost:8080
+//	assetmin inspect -root .
+//
+// The stock binary (cmd/assetmin) links no SSR extractor. A project with SSR
+// modules builds its own binary with Run and Options.NewSSRExtractor:
+//
+//	func main() {
+//		os.Exit(cli.Run(os.Args[1:], os.Stdout, os.Stderr, cli.Options{
+//			NewSSRExtractor: newExtractor, // eg: over github.com/tinywasm/ssr
+//		}))
+//	}
+package cli
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/tinywasm/assetmin"
+)
+
+// Options customize the commands of Run.
+type Options struct {
+	// NewSSRExtractor builds the extractor for the SSR modules under rootDir.
+	// When nil, a project holding SSR module sources is refused rather than
+	// built without them.
+	NewSSRExtractor func(rootDir string) (assetmin.SSRExtractor, error)
+}
+
+// command is one subcommand; it returns the process exit code.
+type command struct {
+	summary string
+	run     func(args []string, stdout, stderr io.Writer, opts Options) int
+}
+
+var commands = map[string]command{
+	"build":   {"bundle every asset once and write it to the output directory", runBuild},
+	"inspect": {"explain the contributors of every output, sprite icon owners, root CSS and fonts", runInspect},
+	"serve":   {"serve the assets from memory, watch the project and live-reload browsers", runServe},
+}
+
+// Run runs the command named by args[0] with the remaining args and returns
+// the process exit code: 0 on success, 1 on failure, 2 on a usage error.
+func Run(args []string, stdout, stderr io.Writer, opts Options) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "help" {
+		usage(stderr, opts)
+		return 2
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "assetmin: unknown command %q\n\n", args[0])
+		usage(stderr, opts)
+		return 2
+	}
+	return cmd.run(args[1:], stdout, stderr, opts)
+}
+
+// usage lists the commands. Without Options.NewSSRExtractor it says that
+// only plain asset files are bundled (see errNoSSRExtractor).
+func usage(w io.Writer, opts Options) {
+	fmt.Fprintln(w, "usage: assetmin <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-8s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintln(w)
+	if opts.NewSSRExtractor == nil {
+		fmt.Fprintln(w, "this binary bundles plain asset files only: a project with SSR module")
+		fmt.Fprintln(w, "sources is refused (build one with cli.Options.NewSSRExtractor)")
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintln(w, `run "assetmin <command> -h" for the flags of a command`)
+}
@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/tinywasm/assetmin"
+)
+
+// runInspect loads the project and prints how every output is composed.
+func runInspect(args []string, stdout, stderr io.Writer, opts Options) int {
+	p := projectFlags{newSSRExtractor: opts.NewSSRExtractor}
+	set := flag.NewFlagSet("assetmin inspect", flag.ContinueOnError)
+	set.SetOutput(stderr)
+	p.register(set)
+	asJSON := set.Bool("json", false, "print the composition as JSON")
+	if err := set.Parse(args); err != nil {
+		return 2
+	}
+	if set.NArg() > 0 {
+		fmt.Fprintf(stderr, "assetmin inspect: unexpected arguments %v\n", set.Args())
+		return 2
+	}
+
+	cfg, err := p.config()
+	if err != nil {
+		fmt.Fprintln(stderr, "assetmin inspect:", err)
+		return 1
+	}
+	am, err := p.load(cfg, func(message ...any) { fmt.Fprintln(stderr, message...) })
+	if err != nil {
+		// Still explain what did load: that is often the point of inspecting.
+		fmt.Fprintln(stderr, "assetmin inspect:", err)
+		if am == nil {
+			return 1
+		}
+	}
+
+	comp := am.Composition()
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(comp); err != nil {
+			fmt.Fprintln(stderr, "assetmin inspect:", err)
+			return 1
+		}
+	} else {
+		printComposition(stdout, comp)
+	}
+	if err != nil {
+		return 1
+	}
+	return 0
+}
+
+func printComposition(w io.Writer, comp *assetmin.Composition) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, a := range comp.Assets {
+		fmt.Fprintf(tw, "%s\n", a.Name)
+		if len(a.Contributors) == 0 {
+			fmt.Fprintf(tw, "  (empty)\n")
+		}
+		for _, c := range a.Contributors {
+			source := c.Source
+			if source == "" {
+				source = "(generated)"
+			}
+			fmt.Fprintf(tw, "  %s\t%s\t%d B\t\n", c.Slot, source, c.Size)
+		}
+	}
+	tw.Flush()
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "sprite icons")
+	if len(comp.Icons) == 0 {
+		fmt.Fprintln(w, "  (none)")
+	}
+	for _, icon := range comp.Icons {
+		shadows := ""
+		if len(icon.Shadowed) > 0 {
+			shadows = "shadows " + strings.Join(icon.Shadowed, ", ")
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\n", icon.ID, icon.Module, shadows)
+	}
+	tw.Flush()
+
+	fmt.Fprintln(w)
+	root := comp.RootCSS
+	switch {
+	case root.Applied == "":
+		fmt.Fprintln(w, "root css: none")
+	case root.Applied == root.Root:
+		fmt.Fprintf(w, "root css: %s (root project", root.Applied)
+		if root.Framework != "" {
+			fmt.Fprintf(w, ", overrides %s", root.Framework)
+		}
+		fmt.Fprintln(w, ")")
+	default:
+		fmt.Fprintf(w, "root css: %s (framework default)\n", root.Applied)
+	}
+
+	if comp.Fonts == nil {
+		fmt.Fprintln(w, "fonts: none")
+	} else {
+		fmt.Fprintf(w, "fonts: %s in %s (%s)\n", comp.Fonts.Family, comp.Fonts.Dir, strings.Join(comp.Fonts.Files, ", "))
+	}
+}
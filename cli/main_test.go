@@ -0,0 +1,258 @@
+//go:build !wasm
+
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tinywasm/assetmin"
+	"github.com/tinywasm/js"
+)
+
+func writeProject(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestBuild_WritesOutputsAndSummary(t *testing.T) {
+	root := writeProject(t, map[string]string{
+		"go.mod":                     "module example.com/app\n",
+		"web/ui/button.css":          ".btn { color : red }",
+		"web/ui/button.js":           "console.log('button')",
+		"web/ui/icons/icon-home.svg": `<svg viewBox="0 0 24 24"><path d="M0 0h24v24H0z"/></svg>`,
+		"web/node_modules/x/x.css":   ".ignored{color:blue}",
+	})
+
+	var stdout, stderr bytes.Buffer
+	if code := Run([]string{"build", "-root", root, "-out", "public", "-prefix", "/static/"}, &stdout, &stderr, Options{}); code != 0 {
+		t.Fatalf("exit %d, stderr:\n%s", code, stderr.String())
+	}
+
+	css, err := os.ReadFile(filepath.Join(root, "public", "style.css"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(css), ".btn{color:red}") {
+		t.Errorf("style.css must hold the minified plain file:\n%s", css)
+	}
+	if strings.Contains(string(css), ".ignored") {
+		t.Errorf("node_modules must not be bundled:\n%s", css)
+	}
+	if _, err := os.Stat(filepath.Join(root, "public", "asset-manifest.json")); err != nil {
+		t.Error(err)
+	}
+	for _, name := range []string{"style.css", "script.js", "icons.svg"} {
+		if !strings.Contains(stdout.String(), name) {
+			t.Errorf("summary must list %s:\n%s", name, stdout.String())
+		}
+	}
+}
+
+func TestBuild_NoMinify(t *testing.T) {
+	root := writeProject(t, map[string]string{"web/a.css": ".a { color : red }"})
+
+	var stdout, stderr bytes.Buffer
+	if code := Run([]string{"build", "-root", root, "-minify=false"}, &stdout, &stderr, Options{}); code != 0 {
+		t.Fatalf("exit %d, stderr:\n%s", code, stderr.String())
+	}
+	css, err := os.ReadFile(filepath.Join(root, "web", "public", "style.css"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(css), ".a { color : red }") {
+		t.Errorf("-minify=false must keep the source as written:\n%s", css)
+	}
+}
+
+func TestBuild_ExitsNonZeroOnErrors(t *testing.T) {
+	root := writeProject(t, map[string]string{"web/a.css": ".a{color:red}", "blocked": "a file, not a directory"})
+
+	for name, args := range map[string][]string{
+		"missing root": {"build", "-root", filepath.Join(root, "missing")},
+		"write error":  {"build", "-root", root, "-out", filepath.Join(root, "blocked", "public")},
+		"bad flag":     {"build", "-nope"},
+		"no command":   {},
+		"unknown":      {"deploy"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := Run(args, &stdout, &stderr, Options{}); code == 0 {
+				t.Errorf("exit 0, want failure; stdout:\n%s", stdout.String())
+			}
+		})
+	}
+}
+
+func TestUsage_StatesTheSSRScope(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	Run(nil, &stdout, &stderr, Options{})
+	if !strings.Contains(stderr.String(), "plain asset files only") {
+		t.Errorf("usage of the stock binary must state it refuses SSR projects:\n%s", stderr.String())
+	}
+
+	stderr.Reset()
+	Run(nil, &stdout, &stderr, Options{NewSSRExtractor: func(string) (assetmin.SSRExtractor, error) { return nil, nil }})
+	if strings.Contains(stderr.String(), "plain asset files only") {
+		t.Errorf("usage with an extractor must not restrict SSR:\n%s", stderr.String())
+	}
+}
+
+func TestInspect_ListsContributors(t *testing.T) {
+	root := writeProject(t, map[string]string{
+		"web/ui/a.css":            ".a{color:red}",
+		"web/ui/icons/icon-x.svg": `<svg viewBox="0 0 24 24"><path d="M0 0h24v24H0z"/></svg>`,
+	})
+
+	var stdout, stderr bytes.Buffer
+	if code := Run([]string{"inspect", "-root", root}, &stdout, &stderr, Options{}); code != 0 {
+		t.Fatalf("exit %d, stderr:\n%s", code, stderr.String())
+	}
+	out := stdout.String()
+	for _, want := range []string{"style.css", "middle", "./web/ui/a.css", "icon-x", "root css: none", "fonts: none"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("inspect output must mention %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestBuild_SecondRunWritesNothing(t *testing.T) {
+	root := writeProject(t, map[string]string{"web/a.css": ".a{color:red}"})
+	args := []string{"build", "-root", root}
+
+	var stdout, stderr bytes.Buffer
+	if code := Run(args, &stdout, &stderr, Options{}); code != 0 {
+		t.Fatalf("exit %d, stderr:\n%s", code, stderr.String())
+	}
+	stdout.Reset()
+	if code := Run(args, &stdout, &stderr, Options{}); code != 0 {
+		t.Fatalf("exit %d, stderr:\n%s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "(0 written,") {
+		t.Errorf("an unchanged project must write nothing:\n%s", stdout.String())
+	}
+}
+
+func TestBuild_OnlyLoadsAssetDirs(t *testing.T) {
+	root := writeProject(t, map[string]string{
+		"web/a.css":                ".a{color:red}",
+		"assets/b.css":             ".b{color:red}",
+		"templates/page.css":       ".template{color:red}",
+		"web/ui/testdata/case.css": ".fixture{color:red}",
+	})
+
+	var stdout, stderr bytes.Buffer
+	if code := Run([]string{"build", "-root", root, "-assets", "web, assets"}, &stdout, &stderr, Options{}); code != 0 {
+		t.Fatalf("exit %d, stderr:\n%s", code, stderr.String())
+	}
+	css, err := os.ReadFile(filepath.Join(root, "web", "public", "style.css"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{".a{color:red}", ".b{color:red}"} {
+		if !strings.Contains(string(css), want) {
+			t.Errorf("style.css must hold %s:\n%s", want, css)
+		}
+	}
+	for _, unwanted := range []string{".template", ".fixture"} {
+		if strings.Contains(string(css), unwanted) {
+			t.Errorf("style.css must not hold %s from outside the asset dirs:\n%s", unwanted, css)
+		}
+	}
+}
+
+func TestBuild_RefusesSSRModulesWithoutExtractor(t *testing.T) {
+	root := writeProject(t, map[string]string{
+		"go.mod":        "module example.com/app\n",
+		"web/a.css":     ".a{color:red}",
+		"ui/button.go":  "package ui\n",
+		"ui/css.go":     "package ui\n\nfunc (b *Button) RenderCSS() string { return `.btn{color:red}` }\n",
+		"ui/testdata/x": "ignored",
+	})
+
+	for name, args := range map[string][]string{
+		"build":   {"build", "-root", root},
+		"inspect": {"inspect", "-root", root},
+		"serve":   {"serve", "-root", root, "-addr", "127.0.0.1:0"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := Run(args, &stdout, &stderr, Options{}); code == 0 {
+				t.Fatalf("exit 0, want failure; stdout:\n%s", stdout.String())
+			}
+			if !strings.Contains(stderr.String(), "links no SSR extractor") || !strings.Contains(stderr.String(), filepath.Join(root, "ui")) {
+				t.Errorf("stderr must name the module dir and the missing extractor:\n%s", stderr.String())
+			}
+		})
+	}
+	if _, err := os.Stat(filepath.Join(root, "web", "public", "style.css")); err == nil {
+		t.Error("a partial bundle must not be written")
+	}
+}
+
+// moduleExtractor stands in for github.com/tinywasm/ssr: ExtractAll serves
+// fixed modules.
+type moduleExtractor struct {
+	modules []*assetmin.SSRAssets
+}
+
+func (e *moduleExtractor) ExtractModule(moduleDir string) (*assetmin.SSRAssets, error) {
+	return nil, nil
+}
+
+func (e *moduleExtractor) ExtractAll() ([]*assetmin.SSRAssets, error) {
+	return e.modules, nil
+}
+
+func TestBuild_BundlesSSRModulesWithExtractor(t *testing.T) {
+	root := writeProject(t, map[string]string{
+		"go.mod":       "module example.com/app\n",
+		"web/a.css":    ".a{color:red}",
+		"ui/button.go": "package ui\n",
+		"ui/css.go":    "package ui\n",
+	})
+	var rootDir string
+	opts := Options{NewSSRExtractor: func(dir string) (assetmin.SSRExtractor, error) {
+		rootDir = dir
+		return &moduleExtractor{modules: []*assetmin.SSRAssets{{
+			ModuleName: "example.com/app/ui",
+			CSS:        ".btn{color:red}",
+			JS:         []*js.Script{{Content: "console.log('button')"}},
+			HTML:       `<button class="btn">Go</button>`,
+		}}}, nil
+	}}
+
+	var stdout, stderr bytes.Buffer
+	if code := Run([]string{"build", "-root", root}, &stdout, &stderr, opts); code != 0 {
+		t.Fatalf("exit %d, stderr:\n%s", code, stderr.String())
+	}
+	if rootDir != root {
+		t.Errorf("extractor built for %q, want %q", rootDir, root)
+	}
+	for name, want := range map[string]string{
+		"style.css":  ".btn{color:red}",
+		"script.js":  "button",
+		"index.html": `<button class="btn">Go</button>`,
+	} {
+		content, err := os.ReadFile(filepath.Join(root, "web", "public", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(content), want) {
+			t.Errorf("%s must hold the module's %s:\n%s", name, want, content)
+		}
+	}
+}
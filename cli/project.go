@@ -0,0 +1,199 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/tinywasm/assetmin"
+)
+
+// errNoSSRExtractor is returned for a project with SSR module sources when
+// Options.NewSSRExtractor is nil.
+var errNoSSRExtractor = errors.New("this assetmin binary links no SSR extractor (github.com/tinywasm/ssr, see Options.NewSSRExtractor); building would drop every module's CSS, JS, HTML and icons")
+
+// projectFlags are the flags shared by every command that loads a project,
+// plus the extractor factory of Options.
+type projectFlags struct {
+	newSSRExtractor func(rootDir string) (assetmin.SSRExtractor, error)
+
+	root    string
+	out     string
+	prefix  string
+	app     string
+	minify  bool
+	dev     bool
+	assets  string
+	timeout time.Duration
+	verbose bool
+}
+
+func (p *projectFlags) register(set *flag.FlagSet) {
+	set.StringVar(&p.root, "root", ".", "project root directory (where go.mod is)")
+	set.StringVar(&p.out, "out", "web/public", "output directory, relative to -root unless absolute")
+	set.StringVar(&p.prefix, "prefix", "", "URL prefix of the asset routes, eg: /static/")
+	set.StringVar(&p.app, "app", "", "application name used by the index.html template")
+	set.StringVar(&p.assets, "assets", "web", "comma-separated directories, relative to -root, holding plain .css/.js/.svg/.html files")
+	set.BoolVar(&p.minify, "minify", true, "minify the outputs")
+	set.BoolVar(&p.dev, "dev", false, "development mode (no HTTP caching, size budgets only logged)")
+	set.DurationVar(&p.timeout, "timeout", 5*time.Minute, "maximum time to wait for SSR module extraction (binaries built with an SSR extractor)")
+	set.BoolVar(&p.verbose, "v", false, "log every processed file")
+}
+
+// config returns the library configuration for the flags.
+func (p *projectFlags) config() (*assetmin.Config, error) {
+	root, err := filepath.Abs(p.root)
+	if err != nil {
+		return nil, err
+	}
+	out := p.out
+	if !filepath.IsAbs(out) {
+		out = filepath.Join(root, out)
+	}
+	var dirs []string
+	for _, dir := range strings.Split(p.assets, ",") {
+		if dir = strings.TrimSpace(dir); dir != "" {
+			dirs = append(dirs, filepath.Clean(dir))
+		}
+	}
+	return &assetmin.Config{
+		RootDir:         root,
+		OutputDir:       out,
+		AppName:         p.app,
+		AssetsURLPrefix: p.prefix,
+		DevMode:         p.dev,
+		PlainAssetDirs:  dirs,
+	}, nil
+}
+
+// assetDirs returns cfg.PlainAssetDirs made absolute against RootDir.
+func assetDirs(cfg *assetmin.Config) []string {
+	dirs := make([]string, 0, len(cfg.PlainAssetDirs))
+	for _, dir := range cfg.PlainAssetDirs {
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(cfg.RootDir, dir)
+		}
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
+
+// inAssetDir reports whether path lies in one of dirs.
+func inAssetDir(path string, dirs []string) bool {
+	for _, dir := range dirs {
+		if path == dir || strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// load creates the AssetMin for cfg and fills it with every plain asset file
+// in the asset dirs and every SSR module. Any extraction error is returned;
+// a project with SSR module sources but no extractor factory is refused.
+func (p *projectFlags) load(cfg *assetmin.Config, logf func(message ...any)) (*assetmin.AssetMin, error) {
+	if p.newSSRExtractor == nil {
+		dir, err := ssrModuleDir(cfg.RootDir, cfg.OutputDir)
+		if err != nil {
+			return nil, err
+		}
+		if dir != "" {
+			return nil, fmt.Errorf("%s holds SSR module sources: %w", dir, errNoSSRExtractor)
+		}
+	}
+
+	am := assetmin.NewAssetMin(cfg)
+	if p.verbose {
+		am.SetLog(logf)
+	}
+	if !p.minify {
+		am.Change(assetmin.MinifyOptionOff)
+	}
+
+	var errs []error
+	files, err := plainAssetFiles(cfg.RootDir, cfg.OutputDir, assetDirs(cfg), am.SupportedExtensions())
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range files {
+		ext := filepath.Ext(path)
+		if err := am.NewFileEvent(filepath.Base(path), ext, path, "create"); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if p.newSSRExtractor != nil {
+		extractor, err := p.newSSRExtractor(cfg.RootDir)
+		if err != nil {
+			return nil, err
+		}
+		am.SetSSRExtractor(extractor)
+		am.LoadSSRModules()
+		ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+		defer cancel()
+		if err := am.WaitForSSRLoadContext(ctx); err != nil {
+			return nil, fmt.Errorf("SSR module extraction did not finish within %s", p.timeout)
+		}
+		if err := am.SSRLoadError(); err != nil {
+			errs = append(errs, fmt.Errorf("SSR extraction: %w", err))
+		}
+	}
+	return am, errors.Join(errs...)
+}
+
+// skipDir reports whether a directory never holds asset sources.
+func skipDir(name string) bool {
+	return name != "." && (strings.HasPrefix(name, ".") || name == "node_modules" || name == "vendor" || name == "testdata")
+}
+
+// walkProject calls fn for every file under root, skipping outDir, hidden
+// directories, dependency folders and testdata.
+func walkProject(root, outDir string, fn func(path string)) error {
+	if _, err := os.Stat(root); errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("root directory %s does not exist", root)
+	}
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path == outDir || (path != root && skipDir(d.Name())) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		fn(path)
+		return nil
+	})
+}
+
+// plainAssetFiles lists the files in the asset dirs with one of the given
+// extensions, in lexical order (see walkProject for what is skipped).
+func plainAssetFiles(root, outDir string, dirs, extensions []string) ([]string, error) {
+	var files []string
+	err := walkProject(root, outDir, func(path string) {
+		if inAssetDir(path, dirs) && slices.Contains(extensions, filepath.Ext(path)) {
+			files = append(files, path)
+		}
+	})
+	return files, err
+}
+
+// ssrModuleDir returns the first directory under root holding SSR module
+// sources, or "" when there is none.
+func ssrModuleDir(root, outDir string) (string, error) {
+	var found string
+	err := walkProject(root, outDir, func(path string) {
+		if dir := filepath.Dir(path); found == "" && filepath.Ext(path) == ".go" && assetmin.HasSSRSources(dir) {
+			found = dir
+		}
+	})
+	return found, err
+}
@@ -0,0 +1,318 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tinywasm/assetmin"
+)
+
+// reloadPath is the server-sent events endpoint the injected client listens on.
+const reloadPath = "/__assetmin/reload"
+
+// modulesPath serves the SSR module registry (AssetMin.Modules).
+const modulesPath = "/__assetmin/modules"
+
+// reloadClient reloads the page whenever the dev server reports a change.
+const reloadClient = `(function(){var es=new EventSource("` + reloadPath + `");es.onmessage=function(){location.reload()};})();`
+
+// runServe loads the project, serves it from memory and reloads connected
+// browsers whenever a watched file changes.
+func runServe(args []string, stdout, stderr io.Writer, opts Options) int {
+	p := projectFlags{newSSRExtractor: opts.NewSSRExtractor}
+	set := flag.NewFlagSet("assetmin serve", flag.ContinueOnError)
+	set.SetOutput(stderr)
+	p.register(set)
+	addr := set.String("addr", "localhost:8080", "address to listen on")
+	interval := set.Duration("interval", 300*time.Millisecond, "how often RootDir is polled for changes")
+	if err := set.Parse(args); err != nil {
+		return 2
+	}
+	if set.NArg() > 0 {
+		fmt.Fprintf(stderr, "assetmin serve: unexpected arguments %v\n", set.Args())
+		return 2
+	}
+
+	cfg, err := p.config()
+	if err != nil {
+		fmt.Fprintln(stderr, "assetmin serve:", err)
+		return 1
+	}
+	cfg.DevMode = true
+	logf := func(message ...any) { fmt.Fprintln(stderr, message...) }
+	am, err := p.load(cfg, logf)
+	if err != nil {
+		// A broken file must not keep the server down: it is reported and
+		// picked up again by the watcher once fixed.
+		fmt.Fprintln(stderr, "assetmin serve:", err)
+		if am == nil {
+			return 1
+		}
+	}
+
+	srv, err := newDevServer(am, cfg, p.newSSRExtractor != nil, logf)
+	if err != nil {
+		fmt.Fprintln(stderr, "assetmin serve:", err)
+		return 1
+	}
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		fmt.Fprintln(stderr, "assetmin serve:", err)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	fmt.Fprintf(stdout, "serving %s on http://%s\n", cfg.RootDir, ln.Addr())
+	if err := srv.serve(ctx, ln, *interval); err != nil {
+		fmt.Fprintln(stderr, "assetmin serve:", err)
+		return 1
+	}
+	return 0
+}
+
+// shutdownTimeout bounds each step of a graceful shutdown: draining the HTTP
+// requests, then closing the AssetMin.
+const shutdownTimeout = 2 * time.Second
+
+// serve answers on ln and polls RootDir every interval until ctx is done,
+// then shuts down gracefully: the reload streams are closed so requests
+// drain, and the AssetMin is closed once they did. It returns after both.
+func (s *devServer) serve(ctx context.Context, ln net.Listener, interval time.Duration) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go s.watch(ctx, interval)
+
+	httpServer := &http.Server{Handler: s}
+	httpServer.RegisterOnShutdown(s.hub.close)
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-ctx.Done()
+		drain, cancelDrain := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancelDrain()
+		if err := httpServer.Shutdown(drain); err != nil {
+			s.log("assetmin serve: shutdown:", err)
+		}
+		closing, cancelClose := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancelClose()
+		if err := s.am.Close(closing); err != nil {
+			s.log("assetmin serve: close:", err)
+		}
+	}()
+
+	err := httpServer.Serve(ln)
+	cancel() // Serve failed on its own: shut down all the same
+	<-stopped
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// devServer serves the in-memory outputs of one AssetMin (its FS view, the
+// files Flush would write) and pushes reloads to browsers.
+type devServer struct {
+	am         *assetmin.AssetMin
+	mux        *http.ServeMux
+	hub        *reloadHub
+	poller     *poller
+	ssrWatcher *assetmin.SSRFileWatcher // nil without an SSR extractor
+	assetDirs  []string                 // where plain asset files are taken from
+	log        func(message ...any)
+}
+
+func newDevServer(am *assetmin.AssetMin, cfg *assetmin.Config, ssr bool, logf func(message ...any)) (*devServer, error) {
+	s := &devServer{am: am, mux: http.NewServeMux(), hub: newReloadHub(), assetDirs: assetDirs(cfg), log: logf}
+
+	am.InjectJS("assetmin-reload", reloadClient)
+	s.mux.Handle("GET "+reloadPath, s.hub)
+	s.mux.HandleFunc("GET "+modulesPath, s.serveModules)
+	s.mux.Handle("GET /", outputHandler(am.FS(), cfg.AssetsURLPrefix))
+
+	// .go files are polled even without an extractor, so an SSR module added
+	// while serving is reported instead of silently missing from the bundle.
+	extensions := append(am.SupportedExtensions(), ".go")
+	if ssr {
+		// The watcher reloads browsers once a batch or rescan changed outputs.
+		s.ssrWatcher = am.NewSSRFileWatcher(func() error {
+			s.hub.broadcast()
+			return nil
+		})
+		extensions = append(extensions, s.ssrWatcher.SupportedExtensions()...)
+	}
+	p, err := newPoller(cfg.RootDir, cfg.OutputDir, extensions)
+	if err != nil {
+		return nil, err
+	}
+	s.poller = p
+	return s, nil
+}
+
+func (s *devServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	s.mux.ServeHTTP(w, req)
+}
+
+// outputHandler serves files by URL: the assets under prefix and the index
+// and standalone scripts at the root both map to their flat output name.
+// Nothing is cached, every request sees the current outputs.
+func outputHandler(files fs.FS, prefix string) http.Handler {
+	server := http.FileServerFS(files)
+	prefix = path.Join("/", prefix)
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if rest, ok := strings.CutPrefix(req.URL.Path, prefix+"/"); ok && prefix != "/" {
+			req = req.Clone(req.Context())
+			req.URL.Path = "/" + rest
+		}
+		w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+		server.ServeHTTP(w, req)
+	})
+}
+
+// serveModules lists the SSR modules as JSON (see AssetMin.Modules).
+func (s *devServer) serveModules(w http.ResponseWriter, req *http.Request) {
+	out, err := json.MarshalIndent(s.am.Modules(), "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	w.Write(out)
+}
+
+// watch polls RootDir until ctx is done.
+func (s *devServer) watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.poll()
+		}
+	}
+}
+
+// poll routes every change since the last call: plain asset files in the
+// asset dirs to NewFileEvent, Go asset sources to the SSR watcher. Browsers
+// are reloaded once per batch of plain files; the SSR watcher reloads them
+// itself, after its changes are applied.
+func (s *devServer) poll() {
+	events, err := s.poller.changes()
+	if err != nil {
+		s.log("watch error:", err)
+		return
+	}
+	reload := false
+	for _, e := range events {
+		name, ext := filepath.Base(e.path), filepath.Ext(e.path)
+		switch {
+		case slices.Contains(s.am.SupportedExtensions(), ext):
+			if !inAssetDir(e.path, s.assetDirs) {
+				continue
+			}
+			if err := s.am.NewFileEvent(name, ext, e.path, e.event); err != nil {
+				s.log(e.event, e.path, "error:", err)
+				continue
+			}
+			reload = true
+		case s.ssrWatcher != nil:
+			if err := s.ssrWatcher.NewFileEvent(name, ext, e.path, e.event); err != nil {
+				s.log(e.event, e.path, "error:", err)
+			}
+		case ext == ".go" && e.event != "remove" && assetmin.HasSSRSources(filepath.Dir(e.path)):
+			s.log(filepath.Dir(e.path), "holds SSR module sources:", errNoSSRExtractor)
+		}
+	}
+	if reload {
+		s.hub.broadcast()
+	}
+}
+
+// reloadHub is the server-sent events endpoint browsers listen on.
+type reloadHub struct {
+	mu      sync.Mutex
+	clients map[chan struct{}]struct{}
+	done    chan struct{} // closed by close
+	once    sync.Once
+}
+
+func newReloadHub() *reloadHub {
+	return &reloadHub{clients: make(map[chan struct{}]struct{}), done: make(chan struct{})}
+}
+
+// close ends every stream, now and to come, so a server shutdown does not
+// wait for the browsers to disconnect.
+func (h *reloadHub) close() {
+	h.once.Do(func() { close(h.done) })
+}
+
+func (h *reloadHub) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ch := make(chan struct{}, 1)
+	h.mu.Lock()
+	h.clients[ch] = struct{}{}
+	h.mu.Unlock()
+	defer func() {
+		h.mu.Lock()
+		delete(h.clients, ch)
+		h.mu.Unlock()
+	}()
+
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case <-h.done:
+			return
+		case <-ch:
+			fmt.Fprint(w, "data: reload\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// broadcast asks every connected browser to reload.
+func (h *reloadHub) broadcast() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.clients {
+		select {
+		case ch <- struct{}{}:
+		default: // a reload is already pending
+		}
+	}
+}
+
+// connected returns the number of listening browsers.
+func (h *reloadHub) connected() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.clients)
+}
@@ -0,0 +1,262 @@
+//go:build !wasm
+
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tinywasm/assetmin"
+)
+
+func get(t *testing.T, url string) string {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+// listenReload connects a browser to the reload endpoint and returns the
+// messages it receives.
+func listenReload(t *testing.T, srv *devServer, url string) <-chan string {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url+reloadPath, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	messages := make(chan string, 4)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			if line := scanner.Text(); strings.HasPrefix(line, "data:") {
+				messages <- line
+			}
+		}
+	}()
+	for deadline := time.Now().Add(2 * time.Second); srv.hub.connected() == 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("reload client never connected")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return messages
+}
+
+func TestServe_ReloadsOnPlainFileChanges(t *testing.T) {
+	root := writeProject(t, map[string]string{"web/ui/a.css": ".a{color:red}"})
+	p := projectFlags{root: root, out: "public", assets: "web", minify: true}
+	cfg, err := p.config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.DevMode = true
+	am, err := p.load(cfg, t.Log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv, err := newDevServer(am, cfg, false, t.Log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+
+	if css := get(t, ts.URL+"/style.css"); !strings.Contains(css, ".a{color:red}") {
+		t.Fatalf("style.css = %q", css)
+	}
+	if js := get(t, ts.URL+"/script.js"); !strings.Contains(js, reloadPath) {
+		t.Errorf("script.js must carry the reload client:\n%s", js)
+	}
+
+	messages := listenReload(t, srv, ts.URL)
+
+	if err := os.WriteFile(filepath.Join(root, "web", "ui", "a.css"), []byte(".a{color:blue;margin:0}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "web", "ui", "b.css"), []byte(".b{color:green}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	srv.poll()
+
+	css := get(t, ts.URL+"/style.css")
+	if !strings.Contains(css, "color:blue") || !strings.Contains(css, ".b{color:green}") {
+		t.Errorf("style.css must reflect the edit and the new file:\n%s", css)
+	}
+	select {
+	case <-messages:
+	case <-time.After(2 * time.Second):
+		t.Fatal("browser was not asked to reload")
+	}
+
+	if err := os.Remove(filepath.Join(root, "web", "ui", "b.css")); err != nil {
+		t.Fatal(err)
+	}
+	srv.poll()
+	if css := get(t, ts.URL+"/style.css"); strings.Contains(css, ".b{") {
+		t.Errorf("removed file must leave the bundle:\n%s", css)
+	}
+}
+
+// sourceExtractor stands in for github.com/tinywasm/ssr: the CSS of the
+// module in root/ui is the content of its css.go, read on every extraction.
+type sourceExtractor struct {
+	root string
+}
+
+func (e *sourceExtractor) ExtractModule(moduleDir string) (*assetmin.SSRAssets, error) {
+	css, err := os.ReadFile(filepath.Join(moduleDir, "css.go"))
+	if err != nil {
+		return nil, err
+	}
+	return &assetmin.SSRAssets{ModuleName: "example.com/app/ui", CSS: string(css), Dir: moduleDir}, nil
+}
+
+func (e *sourceExtractor) ExtractAll() ([]*assetmin.SSRAssets, error) {
+	a, err := e.ExtractModule(filepath.Join(e.root, "ui"))
+	if err != nil {
+		return nil, err
+	}
+	return []*assetmin.SSRAssets{a}, nil
+}
+
+func TestServe_ReloadsOnSSRModuleChanges(t *testing.T) {
+	root := writeProject(t, map[string]string{
+		"go.mod":    "module example.com/app\n",
+		"ui/css.go": ".btn{color:red}",
+	})
+	p := projectFlags{
+		root: root, out: "public", prefix: "/static/", assets: "web", minify: true, timeout: 5 * time.Second,
+		newSSRExtractor: func(dir string) (assetmin.SSRExtractor, error) { return &sourceExtractor{root: dir}, nil },
+	}
+	cfg, err := p.config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.DevMode = true
+	am, err := p.load(cfg, t.Log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv, err := newDevServer(am, cfg, true, t.Log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+
+	if css := get(t, ts.URL+"/static/style.css"); !strings.Contains(css, ".btn{color:red}") {
+		t.Fatalf("style.css = %q", css)
+	}
+	if html := get(t, ts.URL+"/"); !strings.Contains(html, `href="/static/style.css"`) {
+		t.Fatalf("index.html = %q", html)
+	}
+	messages := listenReload(t, srv, ts.URL)
+
+	if err := os.WriteFile(filepath.Join(root, "ui", "css.go"), []byte(".btn{color:blue;margin:0}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	srv.poll()
+
+	if css := get(t, ts.URL+"/static/style.css"); !strings.Contains(css, "color:blue") || strings.Contains(css, "color:red") {
+		t.Errorf("style.css must reflect the edited module source:\n%s", css)
+	}
+	select {
+	case <-messages:
+	case <-time.After(2 * time.Second):
+		t.Fatal("browser was not asked to reload")
+	}
+	if modules := get(t, ts.URL+modulesPath); !strings.Contains(modules, "example.com/app/ui") {
+		t.Errorf("%s must list the module:\n%s", modulesPath, modules)
+	}
+
+	// A .go file that is no asset source changes nothing.
+	if err := os.WriteFile(filepath.Join(root, "main.go"), []byte("package main"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	srv.poll()
+	select {
+	case m := <-messages:
+		t.Errorf("reload after editing main.go: %s", m)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// go.mod: browsers reload once the debounced rescan is applied.
+	if err := os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/app\n\ngo 1.22\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	srv.poll()
+	select {
+	case m := <-messages:
+		t.Errorf("reload before the rescan: %s", m)
+	default:
+	}
+	select {
+	case <-messages:
+	case <-time.After(5 * time.Second):
+		t.Fatal("browser was not asked to reload after the rescan")
+	}
+}
+
+func TestServe_ShutsDownGracefully(t *testing.T) {
+	root := writeProject(t, map[string]string{"web/ui/a.css": ".a{color:red}"})
+	p := projectFlags{root: root, out: "public", assets: "web", minify: true}
+	cfg, err := p.config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	am, err := p.load(cfg, t.Log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv, err := newDevServer(am, cfg, false, t.Log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- srv.serve(ctx, ln, time.Hour) }()
+
+	// An open reload stream must not hold the shutdown.
+	listenReload(t, srv, "http://"+ln.Addr().String())
+	start := time.Now()
+	cancel()
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Fatalf("serve: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("serve did not return")
+	}
+	if elapsed := time.Since(start); elapsed >= shutdownTimeout {
+		t.Errorf("shutdown took %v, waiting for the reload stream", elapsed)
+	}
+	if err := am.FlushToDisk(); !errors.Is(err, assetmin.ErrClosed) {
+		t.Errorf("AssetMin not closed when serve returned: FlushToDisk = %v", err)
+	}
+}
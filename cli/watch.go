@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"sort"
+	"time"
+)
+
+// fileStamp is what the poller compares between scans.
+type fileStamp struct {
+	mod  time.Time
+	size int64
+}
+
+// fileEvent is a change found by the poller; event is create, write or remove.
+type fileEvent struct {
+	path  string
+	event string
+}
+
+// poller detects changes under root by comparing modification times and
+// sizes between scans. It needs no OS notification support. The library has
+// no watcher of its own to reuse: it takes events (NewFileEvent,
+// SSRFileWatcher) from the host's watcher, and none is among its
+// dependencies.
+type poller struct {
+	root       string
+	outDir     string
+	extensions []string
+	files      map[string]fileStamp
+}
+
+func newPoller(root, outDir string, extensions []string) (*poller, error) {
+	p := &poller{root: root, outDir: outDir, extensions: extensions}
+	files, err := p.scan()
+	if err != nil {
+		return nil, err
+	}
+	p.files = files
+	return p, nil
+}
+
+func (p *poller) scan() (map[string]fileStamp, error) {
+	files := make(map[string]fileStamp)
+	err := filepath.WalkDir(p.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil // removed while walking
+			}
+			return err
+		}
+		if d.IsDir() {
+			if path == p.outDir || (path != p.root && skipDir(d.Name())) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !slices.Contains(p.extensions, filepath.Ext(path)) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		files[path] = fileStamp{mod: info.ModTime(), size: info.Size()}
+		return nil
+	})
+	return files, err
+}
+
+// changes rescans root and returns the events since the previous call,
+// sorted by path.
+func (p *poller) changes() ([]fileEvent, error) {
+	files, err := p.scan()
+	if err != nil {
+		return nil, err
+	}
+	var events []fileEvent
+	for path, stamp := range files {
+		old, ok := p.files[path]
+		switch {
+		case !ok:
+			events = append(events, fileEvent{path, "create"})
+		case old != stamp:
+			events = append(events, fileEvent{path, "write"})
+		}
+	}
+	for path := range p.files {
+		if _, ok := files[path]; !ok {
+			events = append(events, fileEvent{path, "remove"})
+		}
+	}
+	p.files = files
+	sort.Slice(events, func(i, j int) bool { return events[i].path < events[j].path })
+	return events, nil
+}
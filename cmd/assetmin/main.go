@@ -0,0 +1,21 @@
+// Command assetmin bundles, minifies and serves the web assets of a Go project
+// without writing a Go program around the library.
+//
+//	assetmin build -root . -out web/public
+//	assetmin serve -root . -addr localhost:8080
+//	assetmin inspect -root .
+//
+// This binary links no SSR extractor: a project with SSR module sources is
+// refused. Such a project builds its own binary with cli.Run and
+// cli.Options.NewSSRExtractor (see package github.com/tinywasm/assetmin/cli).
+package main
+
+import (
+	"os"
+
+	"github.com/tinywasm/assetmin/cli"
+)
+
+func main() {
+	os.Exit(cli.Run(os.Args[1:], os.Stdout, os.Stderr, cli.Options{}))
+}
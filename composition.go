@@ -0,0 +1,99 @@
+package assetmin
+
+import (
+	"sort"
+
+	"github.com/tinywasm/font"
+)
+
+// Composition explains how the current outputs are assembled: who contributes
+// to each asset and in which order, which module won each sprite icon, which
+// :root theme was applied and which fonts are declared.
+type Composition struct {
+	Assets  []AssetComposition `json:"assets"` // sorted by Name
+	Icons   []IconOwner        `json:"icons"`  // sprite order
+	RootCSS RootCSSChoice      `json:"rootCSS"`
+	Fonts   *FontDeclaration   `json:"fonts,omitempty"`
+}
+
+// AssetComposition lists the contributors of one output in bundle order.
+type AssetComposition struct {
+	Name         string        `json:"name"` // eg: style.css
+	Contributors []Contributor `json:"contributors"`
+}
+
+// Contributor is one piece of an output.
+type Contributor struct {
+	Slot   string `json:"slot"`   // init, open, dynamic, middle or close
+	Source string `json:"source"` // module name or source file; "" for generated code
+	Size   int    `json:"size"`   // bytes before minification
+}
+
+// IconOwner is the module whose icon is emitted for ID. Shadowed lists the
+// modules that declare the same ID and lost (modules are resolved by name).
+type IconOwner struct {
+	ID       string   `json:"id"`
+	Module   string   `json:"module"`
+	Shadowed []string `json:"shadowed,omitempty"`
+}
+
+// RootCSSChoice reports the :root theme candidates and the one applied.
+// The root project's candidate always wins over the tinywasm/css framework's.
+type RootCSSChoice struct {
+	Applied   string `json:"applied"`   // module name of the applied candidate; "" when none
+	Root      string `json:"root"`      // root project candidate (fromRoot); "" when absent
+	Framework string `json:"framework"` // tinywasm/css candidate (fromCss); "" when absent
+}
+
+// FontDeclaration is the font family declared by the root project.
+type FontDeclaration struct {
+	Family string   `json:"family"`
+	Dir    string   `json:"dir"`   // relative to RootDir
+	Files  []string `json:"files"` // one per style, copied to OutputDir
+}
+
+// Composition describes how the current outputs are assembled.
+func (c *AssetMin) Composition() *Composition {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	comp := &Composition{}
+	for _, a := range c.allAssets {
+		ac := AssetComposition{Name: a.fileOutputName, Contributors: []Contributor{}}
+		a.mu.RLock()
+		for _, s := range a.segments() {
+			ac.Contributors = append(ac.Contributors, Contributor{Slot: s.slot, Source: s.source, Size: len(s.content)})
+		}
+		a.mu.RUnlock()
+		comp.Assets = append(comp.Assets, ac)
+	}
+	sort.Slice(comp.Assets, func(i, j int) bool { return comp.Assets[i].Name < comp.Assets[j].Name })
+
+	c.spriteMu.RLock()
+	for _, icon := range c.spriteIconsNoLock() {
+		comp.Icons = append(comp.Icons, IconOwner{ID: icon.def.Icon.ID(), Module: icon.module, Shadowed: icon.shadowed})
+	}
+	c.spriteMu.RUnlock()
+
+	if c.fromRoot != nil {
+		comp.RootCSS.Root = c.fromRoot.name
+		comp.RootCSS.Applied = c.fromRoot.name
+	}
+	if c.fromCss != nil {
+		comp.RootCSS.Framework = c.fromCss.name
+		if comp.RootCSS.Applied == "" {
+			comp.RootCSS.Applied = c.fromCss.name
+		}
+	}
+
+	c.fontsMu.RLock()
+	d := c.fonts
+	c.fontsMu.RUnlock()
+	if d.Family() != "" {
+		comp.Fonts = &FontDeclaration{Family: string(d.Family()), Dir: d.Dir()}
+		for s := font.Regular; s <= font.BoldItalic; s++ {
+			comp.Fonts.Files = append(comp.Fonts.Files, d.Family().Face(s)+".ttf")
+		}
+	}
+	return comp
+}
@@ -0,0 +1,81 @@
+package assetmin
+
+import (
+	"bytes"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// cssURLPattern matches a url() reference, quoted or not.
+var cssURLPattern = regexp.MustCompile(`url\(\s*(['"]?)([^'")\s]+)['"]?\s*\)`)
+
+// criticalCSS returns the rules of css that can apply to the given document:
+// every rule whose selectors name only elements, classes and ids present in
+// the markup, plus the statements kept verbatim (@font-face, @keyframes, ...).
+func criticalCSS(css, document string) string {
+	used := newSelectorSet()
+	used.addHTML(document)
+
+	var b strings.Builder
+	writeCSSRules(&b, filterCSSRules(parseCSSRules(css), used.matches))
+	return b.String()
+}
+
+// rebaseCSSURLs resolves the relative url() references of css against base,
+// the URL directory style.css is served from: inlined into the index HTML
+// (served at "/"), they must still point at the files next to the stylesheet
+// under Config.AssetsURLPrefix. Absolute paths, fragments and URLs with a
+// scheme (data:, https:) are left as they are.
+func rebaseCSSURLs(css, base string) string {
+	return cssURLPattern.ReplaceAllStringFunc(css, func(ref string) string {
+		m := cssURLPattern.FindStringSubmatch(ref)
+		quote, target := m[1], m[2]
+		if strings.HasPrefix(target, "/") || strings.HasPrefix(target, "#") || strings.Contains(target, ":") {
+			return ref
+		}
+		return "url(" + quote + path.Join(base, target) + quote + ")"
+	})
+}
+
+// inlineCriticalCSS replaces the blocking stylesheet link of the document with
+// the inlined critical rules plus an asynchronous load of the full stylesheet.
+// Documents whose head does not carry the generated link are left untouched.
+func (h *htmlHandler) inlineCriticalCSS(document []byte, css string) []byte {
+	link := h.generateStylesheetLink()
+	idx := bytes.Index(document, link)
+	if idx == -1 {
+		return document
+	}
+
+	var out bytes.Buffer
+	out.Grow(len(document) + len(css) + 256)
+	out.Write(document[:idx])
+	out.WriteString(`<style>`)
+	out.WriteString(css)
+	out.WriteString(`</style>`)
+	out.Write(h.generateAsyncStylesheetLink())
+	out.Write(document[idx+len(link):])
+	return out.Bytes()
+}
+
+// enableCriticalCSS wires the index HTML to inline the critical rules of the
+// stylesheet, their url() references rebased on the stylesheet's URL. The
+// stylesheet lists the HTML as a dependent so a CSS change recomputes the
+// inlined rules.
+func (c *AssetMin) enableCriticalCSS(hh *htmlHandler) {
+	base := path.Dir(c.mainStyleCssHandler.GetURLPath())
+	c.mainStyleCssHandler.dependents = append(c.mainStyleCssHandler.dependents, c.indexHtmlHandler)
+	c.indexHtmlHandler.transform = func() func([]byte) []byte {
+		// Resolved before the HTML handler locks: reading the stylesheet may
+		// regenerate it, and that must never happen under the HTML lock.
+		css, err := c.mainStyleCssHandler.GetMinifiedContent(c.min)
+		if err != nil {
+			c.writeMessage("critical CSS:", err)
+			return nil
+		}
+		return func(document []byte) []byte {
+			return hh.inlineCriticalCSS(document, rebaseCSSURLs(criticalCSS(string(css), string(document)), base))
+		}
+	}
+}
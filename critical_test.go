@@ -0,0 +1,38 @@
+package assetmin
+
+import "testing"
+
+func TestCriticalCSS_PrunedStylesheetNotRebuiltByInlining(t *testing.T) {
+	am := NewAssetMin(&Config{OutputDir: t.TempDir(), CriticalCSS: true, PruneCSS: true})
+	css, html := am.mainStyleCssHandler, am.indexHtmlHandler
+	build := func() {
+		t.Helper()
+		for _, a := range []*asset{css, html, css, html} {
+			if _, err := a.GetMinifiedContent(am.min); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := am.UpdateSSRModule("example.com/card", ".card{color:red}.unused{color:blue}", nil, `<div class="card"></div>`, nil); err != nil {
+		t.Fatal(err)
+	}
+	build()
+
+	// New CSS re-inlines the critical rules into the HTML; its sources are
+	// the same, so the stylesheet must not be pruned again.
+	if err := am.UpdateSSRModule("example.com/card", ".card{color:green}.unused{color:blue}", nil, `<div class="card"></div>`, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := css.GetMinifiedContent(am.min); err != nil {
+		t.Fatal(err)
+	}
+	if html.isCacheValid() {
+		t.Fatal("the HTML kept critical CSS from the old stylesheet")
+	}
+	if _, err := html.GetMinifiedContent(am.min); err != nil {
+		t.Fatal(err)
+	}
+	if !css.isCacheValid() {
+		t.Error("inlining the critical CSS invalidated the stylesheet it came from")
+	}
+}
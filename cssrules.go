@@ -0,0 +1,413 @@
+package assetmin
+
+import "strings"
+
+// cssRule is one statement of a stylesheet. A qualified rule carries its
+// selector list in prelude and its declarations in body; a grouping at-rule
+// (@media, @supports, ...) carries its nested rules; anything else (@font-face,
+// @keyframes, @import, ...) is kept verbatim in raw.
+type cssRule struct {
+	prelude string
+	body    string
+	rules   []cssRule
+	group   bool
+	raw     string
+}
+
+// groupingAtRules are the at-rules whose block contains further rules.
+var groupingAtRules = []string{"@media", "@supports", "@layer", "@container", "@document"}
+
+// parseCSSRules splits a stylesheet into top-level statements. Comments are
+// dropped; strings and nested blocks are respected. The parser is deliberately
+// forgiving: unbalanced input ends the current statement instead of failing.
+func parseCSSRules(src string) []cssRule {
+	var rules []cssRule
+	var prelude strings.Builder
+
+	for i := 0; i < len(src); i++ {
+		ch := src[i]
+		switch {
+		case ch == '/' && i+1 < len(src) && src[i+1] == '*':
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				i = len(src)
+			} else {
+				i += end + 3
+			}
+		case ch == '"' || ch == '\'':
+			end := skipCSSString(src, i)
+			prelude.WriteString(src[i:end])
+			i = end - 1
+		case ch == ';':
+			if p := strings.TrimSpace(prelude.String()); p != "" {
+				rules = append(rules, cssRule{raw: p + ";"})
+			}
+			prelude.Reset()
+		case ch == '{':
+			end := matchCSSBlock(src, i)
+			p := strings.TrimSpace(prelude.String())
+			body := src[i+1 : end]
+			prelude.Reset()
+			i = end
+			if p == "" {
+				continue
+			}
+			switch {
+			case isGroupingAtRule(p):
+				rules = append(rules, cssRule{prelude: p, rules: parseCSSRules(body), group: true})
+			case strings.HasPrefix(p, "@"):
+				rules = append(rules, cssRule{raw: p + "{" + body + "}"})
+			default:
+				rules = append(rules, cssRule{prelude: p, body: strings.TrimSpace(body)})
+			}
+		default:
+			prelude.WriteByte(ch)
+		}
+	}
+	return rules
+}
+
+func isGroupingAtRule(prelude string) bool {
+	for _, at := range groupingAtRules {
+		if strings.HasPrefix(prelude, at) && (len(prelude) == len(at) || prelude[len(at)] == ' ' || prelude[len(at)] == '(') {
+			return true
+		}
+	}
+	return false
+}
+
+// skipCSSString returns the index just past the string literal starting at i.
+func skipCSSString(src string, i int) int {
+	quote := src[i]
+	for j := i + 1; j < len(src); j++ {
+		switch src[j] {
+		case '\\':
+			j++
+		case quote:
+			return j + 1
+		}
+	}
+	return len(src)
+}
+
+// matchCSSBlock returns the index of the '}' closing the block opened at i,
+// or the last index of src when the block is never closed.
+func matchCSSBlock(src string, i int) int {
+	depth := 0
+	for j := i; j < len(src); j++ {
+		switch src[j] {
+		case '"', '\'':
+			j = skipCSSString(src, j) - 1
+		case '/':
+			if j+1 < len(src) && src[j+1] == '*' {
+				end := strings.Index(src[j+2:], "*/")
+				if end < 0 {
+					return len(src) - 1
+				}
+				j += end + 3
+			}
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return j
+			}
+		}
+	}
+	return len(src) - 1
+}
+
+// writeCSSRules serializes rules back to CSS.
+func writeCSSRules(b *strings.Builder, rules []cssRule) {
+	for _, r := range rules {
+		switch {
+		case r.raw != "":
+			b.WriteString(r.raw)
+		case r.group:
+			b.WriteString(r.prelude)
+			b.WriteByte('{')
+			writeCSSRules(b, r.rules)
+			b.WriteByte('}')
+		default:
+			b.WriteString(r.prelude)
+			b.WriteByte('{')
+			b.WriteString(r.body)
+			b.WriteByte('}')
+		}
+		b.WriteByte('\n')
+	}
+}
+
+// filterCSSRules keeps, in every qualified rule, only the selectors accepted by
+// keep; a rule left without selectors is dropped, and so is a grouping at-rule
+// left without rules. Verbatim statements are always kept.
+func filterCSSRules(rules []cssRule, keep func(selector string) bool) []cssRule {
+	out := make([]cssRule, 0, len(rules))
+	for _, r := range rules {
+		switch {
+		case r.raw != "":
+			out = append(out, r)
+		case r.group:
+			if nested := filterCSSRules(r.rules, keep); len(nested) > 0 {
+				r.rules = nested
+				out = append(out, r)
+			}
+		default:
+			var kept []string
+			for _, sel := range splitSelectorList(r.prelude) {
+				if keep(sel) {
+					kept = append(kept, sel)
+				}
+			}
+			if len(kept) > 0 {
+				r.prelude = strings.Join(kept, ",")
+				out = append(out, r)
+			}
+		}
+	}
+	return out
+}
+
+// splitSelectorList splits a selector list on its top-level commas.
+func splitSelectorList(prelude string) []string {
+	var out []string
+	depth, start := 0, 0
+	for i := 0; i < len(prelude); i++ {
+		switch prelude[i] {
+		case '\\':
+			i++
+		case '(', '[':
+			depth++
+		case ')', ']':
+			depth--
+		case ',':
+			if depth == 0 {
+				if s := strings.TrimSpace(prelude[start:i]); s != "" {
+					out = append(out, s)
+				}
+				start = i + 1
+			}
+		}
+	}
+	if s := strings.TrimSpace(prelude[start:]); s != "" {
+		out = append(out, s)
+	}
+	return out
+}
+
+// selectorSet holds the element names, classes and ids known to be used by
+// some markup or script.
+type selectorSet struct {
+	tags    map[string]bool
+	classes map[string]bool
+	ids     map[string]bool
+	// safelist names are always considered used; an entry ending in "*"
+	// matches every name with that prefix.
+	safelist []string
+}
+
+func newSelectorSet() *selectorSet {
+	return &selectorSet{
+		tags:    make(map[string]bool),
+		classes: make(map[string]bool),
+		ids:     make(map[string]bool),
+	}
+}
+
+// matches reports whether every element, class and id named by the selector
+// is present in the set. Pseudo-classes, pseudo-elements and attribute
+// selectors are ignored, so the check never drops a rule that could apply.
+func (s *selectorSet) matches(selector string) bool {
+	atCompoundStart := true
+	for i := 0; i < len(selector); {
+		ch := selector[i]
+		switch {
+		case ch == ' ' || ch == '>' || ch == '+' || ch == '~' || ch == '\t' || ch == '\n':
+			atCompoundStart = true
+			i++
+		case ch == '[':
+			i = skipBracketed(selector, i, '[', ']')
+			atCompoundStart = false
+		case ch == ':':
+			i++
+			if i < len(selector) && selector[i] == ':' {
+				i++
+			}
+			_, i = readCSSIdent(selector, i)
+			if i < len(selector) && selector[i] == '(' {
+				i = skipBracketed(selector, i, '(', ')')
+			}
+			atCompoundStart = false
+		case ch == '.' || ch == '#':
+			name, next := readCSSIdent(selector, i+1)
+			i = next
+			if ch == '.' && !s.has(s.classes, name) {
+				return false
+			}
+			if ch == '#' && !s.has(s.ids, name) {
+				return false
+			}
+			atCompoundStart = false
+		case ch == '*' || ch == '&':
+			i++
+			atCompoundStart = false
+		default:
+			name, next := readCSSIdent(selector, i)
+			if next == i {
+				i++ // not part of any selector we understand
+				continue
+			}
+			i = next
+			if atCompoundStart && !s.has(s.tags, strings.ToLower(name)) {
+				return false
+			}
+			atCompoundStart = false
+		}
+	}
+	return true
+}
+
+// has reports whether name is in set or safelisted.
+func (s *selectorSet) has(set map[string]bool, name string) bool {
+	if set[name] {
+		return true
+	}
+	for _, safe := range s.safelist {
+		if prefix, ok := strings.CutSuffix(safe, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if safe == name {
+			return true
+		}
+	}
+	return false
+}
+
+// readCSSIdent reads an identifier starting at i, resolving backslash escapes
+// (e.g. `.md\:flex` names the class "md:flex").
+func readCSSIdent(s string, i int) (string, int) {
+	var b strings.Builder
+	for i < len(s) {
+		ch := s[i]
+		switch {
+		case ch == '\\' && i+1 < len(s):
+			b.WriteByte(s[i+1])
+			i += 2
+		case ch == '-' || ch == '_' || ch >= 0x80 ||
+			(ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || (ch >= '0' && ch <= '9'):
+			b.WriteByte(ch)
+			i++
+		default:
+			return b.String(), i
+		}
+	}
+	return b.String(), i
+}
+
+// skipBracketed returns the index just past the bracket pair opened at i.
+func skipBracketed(s string, i int, open, close byte) int {
+	depth := 0
+	for j := i; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case open:
+			depth++
+		case close:
+			depth--
+			if depth == 0 {
+				return j + 1
+			}
+		}
+	}
+	return len(s)
+}
+
+// addHTML records every element name, class and id present in the markup.
+func (s *selectorSet) addHTML(markup string) {
+	for i := 0; i < len(markup); i++ {
+		if markup[i] != '<' || i+1 >= len(markup) {
+			continue
+		}
+		name, next := readCSSIdent(markup, i+1)
+		if name == "" {
+			continue
+		}
+		s.tags[strings.ToLower(name)] = true
+
+		// Attributes until the closing '>', honoring quoted values.
+		for i = next; i < len(markup) && markup[i] != '>'; {
+			attr, after := readCSSIdent(markup, i)
+			if attr == "" {
+				i++
+				continue
+			}
+			i = after
+			for i < len(markup) && markup[i] == ' ' {
+				i++
+			}
+			if i >= len(markup) || markup[i] != '=' {
+				continue
+			}
+			i++
+			for i < len(markup) && markup[i] == ' ' {
+				i++
+			}
+			var value string
+			if i < len(markup) && (markup[i] == '"' || markup[i] == '\'') {
+				end := strings.IndexByte(markup[i+1:], markup[i])
+				if end < 0 {
+					return
+				}
+				value = markup[i+1 : i+1+end]
+				i += end + 2
+			} else {
+				start := i
+				for i < len(markup) && markup[i] != ' ' && markup[i] != '>' {
+					i++
+				}
+				value = markup[start:i]
+			}
+			switch strings.ToLower(attr) {
+			case "class":
+				for _, class := range strings.Fields(value) {
+					s.classes[class] = true
+				}
+			case "id":
+				s.ids[strings.TrimSpace(value)] = true
+			}
+		}
+	}
+}
+
+// addScript records every word of a script as a possible element name, class
+// and id. Scripts build class names in ways no parser can follow, so the set
+// errs on the side of keeping rules: any token that could name a selector does.
+func (s *selectorSet) addScript(src string) {
+	isWord := func(ch byte) bool {
+		return ch == '-' || ch == '_' || ch == ':' || ch == '/' || ch == '@' || ch >= 0x80 ||
+			(ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || (ch >= '0' && ch <= '9')
+	}
+	add := func(word string) {
+		s.tags[strings.ToLower(word)] = true
+		s.classes[word] = true
+		s.ids[word] = true
+	}
+	for i := 0; i < len(src); {
+		if !isWord(src[i]) {
+			i++
+			continue
+		}
+		start := i
+		for i < len(src) && isWord(src[i]) {
+			i++
+		}
+		word := src[start:i]
+		add(word)
+		for _, part := range strings.FieldsFunc(word, func(r rune) bool { return r == ':' || r == '/' || r == '@' }) {
+			add(part)
+		}
+	}
+}
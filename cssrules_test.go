@@ -0,0 +1,57 @@
+package assetmin
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSelectorSet_Matches(t *testing.T) {
+	used := newSelectorSet()
+	used.addHTML(`<!doctype html><html><body><nav class="menu md:flex" id='top'><a href="/" class=link>x</a></nav></body></html>`)
+
+	cases := map[string]bool{
+		"nav":                    true,
+		".menu":                  true,
+		"nav.menu > a.link":      true,
+		"#top a:hover":           true,
+		`.md\:flex`:              true,
+		":root":                  true,
+		"*":                      true,
+		"a::before":              true,
+		`a[href^="/"]`:           true,
+		"table":                  false,
+		".menu .missing":         false,
+		"#bottom":                false,
+		"nav:not(.x) .ghost":     false,
+		"body > section.menu":    false,
+		"html body nav#top.menu": true,
+	}
+	for sel, want := range cases {
+		if got := used.matches(sel); got != want {
+			t.Errorf("matches(%q) = %v, want %v", sel, got, want)
+		}
+	}
+}
+
+func TestParseCSSRules_RoundTrip(t *testing.T) {
+	src := `/* theme */ @charset "utf-8"; .a,.b{content:"}{"} @media (min-width:1px){.a{x:1}} @font-face{font-family:X} @keyframes k{from{o:0}to{o:1}}`
+
+	rules := parseCSSRules(src)
+	if len(rules) != 5 {
+		t.Fatalf("got %d rules, want 5: %#v", len(rules), rules)
+	}
+
+	keepA := func(sel string) bool { return sel == ".a" }
+	var b strings.Builder
+	writeCSSRules(&b, filterCSSRules(rules, keepA))
+	got := b.String()
+
+	for _, want := range []string{`@charset "utf-8";`, `.a{content:"}{"}`, "@media (min-width:1px){.a{x:1}", "@font-face{font-family:X}", "@keyframes k{from{o:0}to{o:1}}"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, ".b") || strings.Contains(got, "theme") {
+		t.Errorf("output must drop .b and comments:\n%s", got)
+	}
+}
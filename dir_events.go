@@ -0,0 +1,73 @@
+package assetmin
+
+import (
+	"io/fs"
+	"path/filepath"
+	"slices"
+)
+
+// dirBatch expands an event on a directory. Removing or renaming it (the
+// event names the old path) drops every entry below it, in every handler,
+// sprite icons included; creating it (or moving it in) reads every asset file
+// below it, in SSR mode only those in Config.PlainAssetDirs. Other events
+// change nothing.
+func (c *AssetMin) dirBatch(dir, event string, ssr bool) *eventBatch {
+	switch event {
+	case "remove", "delete", "rename":
+		return &eventBatch{dirs: map[string]bool{dir: true}}
+	case "create":
+		b := &eventBatch{files: make(map[string]fileEvent)}
+		filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil // unreadable entries are skipped, like a missed event
+			}
+			if d.IsDir() {
+				if path != dir && filepath.Clean(path) == filepath.Clean(c.OutputDir) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if ssr && c.plainDir(path) == "" {
+				return nil // a module source: re-extracted by the SSR watcher
+			}
+			if ext := filepath.Ext(path); slices.Contains(c.SupportedExtensions(), ext) {
+				b.files[path] = fileEvent{d.Name(), ext, "create"}
+			}
+			return nil
+		})
+		return b
+	}
+	return &eventBatch{}
+}
+
+// removeUnder drops every entry whose path lies below dir, and the icons added
+// from .svg files there, and returns the assets that changed. Entries are
+// matched under both key forms (see contentKey): dir may be a plain dir, lie
+// in one or hold one. It assumes the caller holds c.mu.
+func (c *AssetMin) removeUnder(dir string) []*asset {
+	keys := []string{dir}
+	if key := c.relativeKey(dir); key != "" {
+		keys = append(keys, key)
+	}
+	var changed []*asset
+	for _, path := range sortedKeys(c.allAssets) {
+		a := c.allAssets[path]
+		for _, key := range keys {
+			if a.removeUnder(key) && !slices.Contains(changed, a) {
+				changed = append(changed, a)
+			}
+		}
+	}
+	icons := false
+	for path, id := range c.iconFiles {
+		if pathUnder(path, dir) {
+			c.removeIconFile(id)
+			delete(c.iconFiles, path)
+			icons = true
+		}
+	}
+	if icons && !slices.Contains(changed, c.spriteSvgHandler) {
+		changed = append(changed, c.spriteSvgHandler)
+	}
+	return changed
+}
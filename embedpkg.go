@@ -0,0 +1,162 @@
+package assetmin
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"text/template"
+)
+
+// embedAssetsDir is the directory, inside the generated package, holding the
+// embedded copies of the outputs.
+const embedAssetsDir = "assets"
+
+// embedSourceFile is the generated Go file of the embed package.
+const embedSourceFile = "assets_embed.go"
+
+// GenerateEmbedPackage writes a Go package into dir that embeds the current
+// outputs with //go:embed, so a production binary needs neither OutputDir nor
+// the SSR extraction at startup. The package holds:
+//   - Manifest: every output by logical name, with URL, hash, media type and
+//     the Cache-Control policy RegisterRoutes applies in production;
+//   - Handler(): an http.Handler serving them by URL;
+//   - FS: the embedded files.
+//
+// dir/assets is replaced on every call; pkg is the package name (default: the
+// base name of dir).
+func (c *AssetMin) GenerateEmbedPackage(dir, pkg string) error {
+	const e = "GenerateEmbedPackage: "
+	if pkg == "" {
+		pkg = filepath.Base(dir)
+	}
+	if !token.IsIdentifier(pkg) {
+		return fmt.Errorf(e+"invalid package name %q", pkg)
+	}
+
+	files, _, err := c.outputFiles()
+	if err != nil {
+		return fmt.Errorf(e+"%w", err)
+	}
+	manifest, err := parseManifest(files[manifestFileName].content)
+	if err != nil {
+		return fmt.Errorf(e+"%w", err)
+	}
+
+	assetsDir := filepath.Join(dir, embedAssetsDir)
+	if err := os.RemoveAll(assetsDir); err != nil {
+		return fmt.Errorf(e+"%w", err)
+	}
+	data := embedTemplateData{Package: pkg, Dir: embedAssetsDir}
+	for _, entry := range manifest.Assets {
+		f, ok := files[entry.Path]
+		if !ok {
+			continue
+		}
+		if err := FileWrite(filepath.Join(assetsDir, filepath.FromSlash(entry.Path)), *bytes.NewBuffer(f.content)); err != nil {
+			return fmt.Errorf(e+"%w", err)
+		}
+		data.Assets = append(data.Assets, embedAsset{
+			ManifestEntry: entry,
+			File:          path.Join(embedAssetsDir, entry.Path),
+			CacheControl:  cacheControl(entry.MediaType, false),
+		})
+	}
+
+	var src bytes.Buffer
+	if err := embedTemplate.Execute(&src, data); err != nil {
+		return fmt.Errorf(e+"%w", err)
+	}
+	formatted, err := format.Source(src.Bytes())
+	if err != nil {
+		return fmt.Errorf(e+"formatting generated source: %w", err)
+	}
+	return FileWrite(filepath.Join(dir, embedSourceFile), *bytes.NewBuffer(formatted))
+}
+
+type embedTemplateData struct {
+	Package string
+	Dir     string
+	Assets  []embedAsset // sorted by Name
+}
+
+type embedAsset struct {
+	ManifestEntry
+	File         string // path inside the embedded FS
+	CacheControl string
+}
+
+var embedTemplate = template.Must(template.New("embed").Parse(`// Code generated by assetmin GenerateEmbedPackage. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"embed"
+	"net/http"
+)
+
+// FS holds the embedded outputs under "{{.Dir}}/".
+//
+//go:embed {{.Dir}}
+var FS embed.FS
+
+// Asset describes one embedded output.
+type Asset struct {
+	File         string // path inside FS
+	URL          string // "" when not served over HTTP (the inline sprite)
+	MediaType    string
+	Hash         string // hex SHA-256 of the content
+	Size         int64
+	CacheControl string
+}
+
+// Manifest lists every embedded output by logical name, eg: "style.css".
+var Manifest = map[string]Asset{
+{{- range .Assets}}
+	{{printf "%q" .Name}}: {File: {{printf "%q" .File}}, URL: {{printf "%q" .URL}}, MediaType: {{printf "%q" .MediaType}}, Hash: {{printf "%q" .Hash}}, Size: {{.Size}}, CacheControl: {{printf "%q" .CacheControl}}},
+{{- end}}
+}
+
+// URL returns the URL of the asset with the given logical name, or "".
+func URL(name string) string {
+	return Manifest[name].URL
+}
+
+// Handler serves the embedded outputs by URL with their media type and
+// Cache-Control policy. Unknown paths answer 404. Mount it at "/": the URLs
+// are full paths, and the index is served at "/".
+func Handler() http.Handler {
+	byURL := make(map[string]Asset, len(Manifest))
+	for _, a := range Manifest {
+		if a.URL != "" {
+			byURL[a.URL] = a
+		}
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		a, ok := byURL[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		content, err := FS.ReadFile(a.File)
+		if err != nil {
+			http.Error(w, "Error reading embedded asset", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", a.MediaType)
+		w.Header().Set("Cache-Control", a.CacheControl)
+		if r.Method == http.MethodHead {
+			return
+		}
+		w.Write(content)
+	})
+}
+`))
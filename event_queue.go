@@ -0,0 +1,264 @@
+package assetmin
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"time"
+)
+
+// eventBatch is the work of one or more file events, coalesced: one entry per
+// file path and per module directory, the latest event winning.
+type eventBatch struct {
+	dirs    map[string]bool        // removed directories: every entry below them goes, before files apply
+	files   map[string]fileEvent   // plain asset files (NewFileEvent), by path
+	modules map[string]moduleEvent // SSR module directories to re-extract or retract (see retractSSRDir)
+	images  map[string]bool        // module directories whose image.go changed
+	compile bool                   // an SSR .go change: the onSSRCompile hook runs once
+	reload  func()                 // browser reload, once the batch changed something
+}
+
+// moduleEvent is what happened to an SSR module directory.
+type moduleEvent uint8
+
+const (
+	moduleChanged       moduleEvent = iota // an asset source changed: re-extract
+	moduleSourceRemoved                    // an asset source went away: re-extract, or remove the module of the directory
+	moduleDirRemoved                       // the directory went away: remove every module loaded from it or below it
+)
+
+type fileEvent struct {
+	fileName, extension, event string
+}
+
+// removal reports whether the file went away (removed or renamed from).
+func (e fileEvent) removal() bool {
+	return e.event == "remove" || e.event == "delete" || e.event == "rename"
+}
+
+// merge adds the events of b; those of b win. A removed directory supersedes
+// the events queued below it.
+func (p *eventBatch) merge(b *eventBatch) {
+	for dir := range b.dirs {
+		if p.dirs == nil {
+			p.dirs = make(map[string]bool)
+		}
+		p.dirs[dir] = true
+		for path := range p.files {
+			if pathUnder(path, dir) {
+				delete(p.files, path)
+			}
+		}
+	}
+	for path, ev := range b.files {
+		if p.files == nil {
+			p.files = make(map[string]fileEvent)
+		}
+		p.files[path] = ev
+	}
+	for dir, ev := range b.modules {
+		if p.modules == nil {
+			p.modules = make(map[string]moduleEvent)
+		}
+		p.modules[dir] = ev
+	}
+	for dir := range b.images {
+		if p.images == nil {
+			p.images = make(map[string]bool)
+		}
+		p.images[dir] = true
+	}
+	p.compile = p.compile || b.compile
+	if b.reload != nil {
+		p.reload = b.reload
+	}
+}
+
+// queueEvents adds b to the pending batch and restarts the
+// Config.EventDebounce window; when it expires the batch is applied and its
+// error logged. Without a window b is applied at once and its error returned.
+// Until a queued batch is applied, WaitForSSRLoad and Close wait for it as
+// for a load.
+func (c *AssetMin) queueEvents(b *eventBatch) error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return ErrClosed
+	}
+	debounce := c.EventDebounce
+	if debounce <= 0 {
+		c.mu.Unlock()
+		c.eventsMu.Lock()
+		defer c.eventsMu.Unlock()
+		return c.applyEvents(b)
+	}
+	defer c.mu.Unlock()
+	if c.pendingEvents == nil {
+		c.pendingEvents = &eventBatch{}
+	}
+	c.pendingEvents.merge(b)
+	if c.eventTimer != nil && c.eventTimer.Stop() {
+		c.eventTimer.Reset(debounce)
+		return nil
+	}
+	// No timer, or it already fired: that run may or may not take b, so arm
+	// a new one; a run that finds nothing pending does nothing.
+	c.ssrLoading.Add(1)
+	c.eventTimer = time.AfterFunc(debounce, func() {
+		defer c.ssrLoading.Done()
+		if err := c.FlushEvents(); err != nil && !errors.Is(err, ErrClosed) {
+			c.Logger("file event error:", err)
+		}
+	})
+	return nil
+}
+
+// FlushEvents applies the events queued under Config.EventDebounce now,
+// instead of when the window expires, and returns their error. After Close it
+// returns ErrClosed.
+func (c *AssetMin) FlushEvents() error {
+	c.eventsMu.Lock()
+	defer c.eventsMu.Unlock()
+	c.mu.Lock()
+	b := c.pendingEvents
+	c.pendingEvents = nil
+	closed := c.closed
+	c.mu.Unlock()
+	if closed {
+		return ErrClosed
+	}
+	if b == nil {
+		return nil
+	}
+	return c.applyEvents(b)
+}
+
+// stopEvents drops the queued events. It assumes the caller holds c.mu.
+func (c *AssetMin) stopEvents() {
+	if c.eventTimer != nil && c.eventTimer.Stop() {
+		c.ssrLoading.Done()
+	}
+	c.eventTimer = nil
+	c.pendingEvents = nil
+}
+
+// applyEvents applies a batch: every file updates its asset and every module
+// is re-extracted before the affected outputs are regenerated, once each.
+// It assumes the caller holds c.eventsMu. After Close it returns ErrClosed.
+func (c *AssetMin) applyEvents(b *eventBatch) error {
+	done, err := c.track()
+	if err != nil {
+		return err
+	}
+	defer done()
+	var errs []error
+	if b.compile {
+		c.mu.Lock()
+		fn := c.onSSRCompile
+		c.mu.Unlock()
+		if fn != nil {
+			if err := fn(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	if len(b.files) > 0 || len(b.dirs) > 0 {
+		errs = append(errs, c.applyFileEvents(b.dirs, b.files)...)
+	}
+
+	changed := false
+	refresh := make(map[string]bool)
+	for _, dir := range sortedKeys(b.modules) {
+		var exts map[string]bool
+		var ok bool
+		var err error
+		if ev := b.modules[dir]; ev != moduleChanged {
+			exts, ok, err = c.retractSSRDir(context.Background(), dir, ev == moduleDirRemoved)
+		} else {
+			exts, err = c.reloadSSRModule(context.Background(), dir)
+			ok = true
+		}
+		for ext := range exts {
+			refresh[ext] = true
+		}
+		if err != nil {
+			c.Logger("SSR hot reload error:", dir, err)
+			errs = append(errs, err)
+			continue
+		}
+		changed = changed || ok
+	}
+	c.refreshExtensions(refresh)
+
+	for _, dir := range sortedKeys(b.images) {
+		if err := c.imageProcessor.ReloadModule(dir); err != nil {
+			c.Logger("image hot reload error:", dir, err)
+			errs = append(errs, err)
+			continue
+		}
+		changed = true
+	}
+
+	if changed && b.reload != nil {
+		b.reload()
+	}
+	return errors.Join(errs...)
+}
+
+// applyFileEvents drops the entries below the removed directories and updates
+// the assets of the given files, then regenerates each affected asset once.
+func (c *AssetMin) applyFileEvents(dirs map[string]bool, files map[string]fileEvent) []error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var errs []error
+	var touched []*asset
+	if c.ssrExtractor == nil {
+		// No SSR load will ever release the warm-started outputs: the plain
+		// files are the whole content, so the first event replaces them.
+		for _, a := range c.allAssets {
+			if a.release() {
+				touched = append(touched, a)
+			}
+		}
+	}
+	for _, dir := range sortedKeys(dirs) {
+		for _, fh := range c.removeUnder(dir) {
+			if !slices.Contains(touched, fh) {
+				touched = append(touched, fh)
+			}
+		}
+	}
+	// Removals go first: the "create" of a rename to a path that sorts
+	// earlier would otherwise find the old entry (an icon id) still there.
+	paths := sortedKeys(files)
+	sort.SliceStable(paths, func(i, j int) bool {
+		return files[paths[i]].removal() && !files[paths[j]].removal()
+	})
+	for _, path := range paths {
+		fh, err := c.applyFileEvent(path, files[path])
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if fh != nil && !slices.Contains(touched, fh) {
+			touched = append(touched, fh)
+		}
+	}
+	for _, fh := range touched {
+		if err := c.processAsset(fh); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -1,12 +1,11 @@
 package assetmin
 
 import (
-	"bytes"
 	"errors"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
-	"time"
 
 	"github.com/tinywasm/js"
 )
@@ -48,84 +47,171 @@ func (c *AssetMin) UpdateFileContentInMemory(filePath, extension, event string,
 }
 
 // event: create, remove, write, rename
+//
+// With Config.EventDebounce set the event is queued (see queueEvents) and
+// applied later; otherwise it is applied before returning.
 func (c *AssetMin) NewFileEvent(fileName, extension, filePath, event string) error {
-	// In SSR mode, delegate to external server and return early
 	c.mu.Lock()
 	ssr := c.isSSRMode()
+	closed := c.closed
 	c.mu.Unlock()
+	if closed {
+		return ErrClosed
+	}
 
-	if ssr {
+	// In SSR mode a .go file is compiled wherever it lies, plain asset dirs
+	// included; other extensions there are no assets either.
+	if ssr && extension != "" && !slices.Contains(c.SupportedExtensions(), extension) {
 		if extension == ".go" {
-			c.mu.Lock()
-			fn := c.onSSRCompile
-			c.mu.Unlock()
-			if fn != nil {
-				return fn()
-			}
-			return nil
+			return c.queueEvents(&eventBatch{compile: true})
 		}
+		return nil
+	}
 
-		// Hot-reload embedded files without rebuilding WASM
+	// In SSR mode, delegate to external server, except for plain asset dirs
+	if ssr && c.plainDir(filePath) == "" {
 		switch extension {
+		case "":
+			if c.touchesPlainDir(filePath) {
+				// A directory holding plain asset dirs: see dirBatch
+				return c.queueEvents(c.dirBatch(filePath, event, ssr))
+			}
 		case ".css", ".js", ".svg", ".html":
-			dir := filepath.Dir(filePath)
-			time.Sleep(20 * time.Millisecond) // Timing guard for OS file operations
-			_ = c.ReloadSSRModule(dir)        // Encapsulates refresh internally
+			// Hot-reload embedded files without rebuilding WASM. A module
+			// that fails to re-extract is logged by applyEvents and keeps its
+			// last assets; the event itself is not an error.
+			err := c.queueEvents(&eventBatch{modules: map[string]moduleEvent{filepath.Dir(filePath): moduleChanged}})
+			if errors.Is(err, ErrClosed) {
+				return err
+			}
 			return nil
 		}
 		return nil
 	}
 
+	if filePath == "" {
+		return errors.New("NewFileEvent " + extension + " " + event + "filePath is empty")
+	}
+	if extension == "" {
+		// A directory: see dirBatch
+		return c.queueEvents(c.dirBatch(filePath, event, ssr))
+	}
+	return c.queueEvents(&eventBatch{files: map[string]fileEvent{filePath: {fileName, extension, event}}})
+}
+
+// NewFileRenameEvent is the NewFileEvent of a rename whose old and new paths
+// are both known: the entry of oldPath is removed and newPath is read in its
+// place, in whichever asset each extension belongs to (a rename from .css to
+// .js moves the file from style.css to script.js). When newPath is a
+// directory, every entry below oldPath is removed and every asset file below
+// newPath is read. Like NewFileEvent it is queued under Config.EventDebounce;
+// RenameFile applies it at once.
+func (c *AssetMin) NewFileRenameEvent(oldPath, newPath string) error {
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	ssr := c.isSSRMode()
+	closed := c.closed
+	c.mu.Unlock()
+	if closed {
+		return ErrClosed
+	}
+	return c.queueEvents(c.renameBatch(oldPath, newPath, ssr))
+}
+
+// RenameFile moves the entry of oldPath to newPath now, see
+// NewFileRenameEvent. A queued event for either path is superseded.
+func (c *AssetMin) RenameFile(oldPath, newPath string) error {
+	c.eventsMu.Lock()
+	defer c.eventsMu.Unlock()
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return ErrClosed
+	}
+	ssr := c.isSSRMode()
+	if p := c.pendingEvents; p != nil {
+		for path := range p.files {
+			if pathUnder(path, oldPath) || pathUnder(path, newPath) {
+				delete(p.files, path)
+			}
+		}
+	}
+	c.mu.Unlock()
+	return c.applyEvents(c.renameBatch(oldPath, newPath, ssr))
+}
 
+// renameBatch is the batch of a rename: the old path goes away and the new
+// one is created. In SSR mode the module directories (those outside
+// Config.PlainAssetDirs) are re-extracted instead.
+func (c *AssetMin) renameBatch(oldPath, newPath string, ssr bool) *eventBatch {
+	if info, err := os.Stat(newPath); err == nil && info.IsDir() && (!ssr || c.touchesPlainDir(oldPath) || c.touchesPlainDir(newPath)) {
+		b := c.dirBatch(oldPath, "rename", ssr)
+		b.merge(c.dirBatch(newPath, "create", ssr))
+		return b
+	}
+	b := &eventBatch{}
+	for _, r := range [2]struct{ path, event string }{{oldPath, "rename"}, {newPath, "create"}} {
+		ext := filepath.Ext(r.path)
+		if r.path == "" || !slices.Contains(c.SupportedExtensions(), ext) {
+			continue
+		}
+		if ssr && c.plainDir(r.path) == "" {
+			b.merge(&eventBatch{modules: map[string]moduleEvent{filepath.Dir(r.path): moduleChanged}})
+			continue
+		}
+		b.merge(&eventBatch{files: map[string]fileEvent{r.path: {filepath.Base(r.path), ext, r.event}}})
+	}
+	return b
+}
+
+// applyFileEvent updates the memory content of one asset file and returns the
+// asset to regenerate, nil when there is none. It assumes the caller holds c.mu.
+func (c *AssetMin) applyFileEvent(filePath string, ev fileEvent) (*asset, error) {
 	// Check if filePath matches any of our output paths to avoid infinite recursion
 	if c.isOutputPath(filePath) {
 		//c.writeMessage("Skipping output file:", filePath)
-		return nil
+		return nil, nil
 	}
 
-	var e = "NewFileEvent " + extension + " " + event
-	if filePath == "" {
-		return errors.New(e + "filePath is empty")
-	}
-
-	c.writeMessage(event, filePath)
+	var e = "NewFileEvent " + ev.extension + " " + ev.event
 
-	// Increase sleep duration significantly to allow file system operations (like write after rename) to settle
-	// fail when time is < 10ms
-	time.Sleep(20 * time.Millisecond) // Increased from 10ms
+	c.writeMessage(ev.event, filePath)
 
 	var content []byte
 	var err error
 
 	// For delete/remove events, we don't need to read file content since file no longer exists
-	if event == "remove" || event == "delete" {
+	removed := ev.removal()
+	if removed {
 		content = []byte{} // Empty content for delete events
 	} else {
 		// read file content from filePath for other events
 		content, err = os.ReadFile(filePath)
 		if err != nil {
-			return errors.New(e + err.Error())
+			return nil, errors.New(e + err.Error())
 		}
 	}
 
-	if extension == ".svg" && filepath.Base(filePath) != c.faviconSvgHandler.fileOutputName {
+	if ev.extension == ".svg" && filepath.Base(filePath) != c.faviconSvgHandler.fileOutputName {
 		// Individual SVG files (not icons from Go modules) are treated as icons for the sprite
-		if err := c.addIconFile(fileName, string(content)); err != nil {
-			return err
+		if removed {
+			c.removeIconFile(ev.fileName)
+			delete(c.iconFiles, filePath)
+			return c.spriteSvgHandler, nil
+		}
+		if err := c.addIconFile(ev.fileName, string(content)); err != nil {
+			return nil, err
+		}
+		if c.iconFiles == nil {
+			c.iconFiles = make(map[string]string)
 		}
-		return c.processAsset(c.spriteSvgHandler)
+		c.iconFiles[filePath] = ev.fileName
+		return c.spriteSvgHandler, nil
 	}
-	fh, err := c.UpdateFileContentInMemory(filePath, extension, event, content) // Update contentMiddle
+	fh, err := c.UpdateFileContentInMemory(c.contentKey(filePath), ev.extension, ev.event, content) // Update contentMiddle
 	if err != nil {
-		return errors.New(e + err.Error())
+		return nil, errors.New(e + err.Error())
 	}
-	if fh == nil {
-		return nil
-	}
-
-	return c.processAsset(fh)
+	return fh, nil
 }
 
 func (c *AssetMin) processAsset(fh *asset) error {
@@ -134,9 +220,31 @@ func (c *AssetMin) processAsset(fh *asset) error {
 		return err
 	}
 
-	// 2. Write to disk only if enabled
-	if c.diskMirrored {
-		return FileWrite(fh.outputPath, *bytes.NewBuffer(fh.GetCachedMinified()))
+	// 2. Write to disk only if enabled (and never after Close)
+	if c.diskMirrored && !c.closed {
+		if !c.emitted(fh) {
+			return c.removeOutput(fh)
+		}
+		if _, err := c.writeOutput(fh.outputPath, fh.GetCachedMinified()); err != nil {
+			return err
+		}
+		c.ownedOutputs()[fh.outputPath] = true
+		if fh.sourceMap && c.WriteSourceMaps {
+			if _, err := c.writeOutput(fh.outputPath+".map", fh.GetSourceMap()); err != nil {
+				return err
+			}
+			c.ownedOutputs()[fh.outputPath+".map"] = true
+		}
+	}
+
+	// 3. Outputs derived from this one (critical CSS in the HTML head) went
+	// stale if its content changed; bring them up to date as well.
+	for _, d := range slices.Concat(fh.dependents, fh.sourceDependents) {
+		if !d.isCacheValid() {
+			if err := c.processAsset(d); err != nil {
+				return err
+			}
+		}
 	}
 	return nil
 }
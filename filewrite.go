@@ -11,6 +11,10 @@ import (
 // pathFile e.g., "theme/htmlMainFileName"
 // data e.g., *bytes.Buffer
 // NOTE: The buffer data will be cleared after writing the file
+//
+// The content is written to a temporary file in the same directory and renamed
+// over pathFile, so readers (a static server, a crash) never observe a
+// half-written file: they see either the previous content or the new one.
 func FileWrite(pathFile string, data bytes.Buffer) error {
 	const e = "FileWrite "
 
@@ -20,17 +24,27 @@ func FileWrite(pathFile string, data bytes.Buffer) error {
 		return errors.New(e + "while creating directory " + err.Error())
 	}
 
-	dst, err := os.Create(pathFile)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(pathFile)+".*.tmp")
 	if err != nil {
 		return errors.New(e + "while creating file " + err.Error())
 	}
-	defer dst.Close()
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // no-op once renamed
 
 	// Copy the uploaded ContentFile to the filesystem at the specified destination
-	_, err = io.Copy(dst, &data)
-	if err != nil {
+	if _, err = io.Copy(tmp, &data); err != nil {
+		tmp.Close()
 		return errors.New(e + "failed to write the file " + pathFile + " to the destination " + err.Error())
 	}
-
+	if err := tmp.Close(); err != nil {
+		return errors.New(e + "failed to write the file " + pathFile + " " + err.Error())
+	}
+	// CreateTemp creates 0600; outputs are served and deployed, keep them world-readable.
+	if err := os.Chmod(tmpPath, 0644); err != nil {
+		return errors.New(e + err.Error())
+	}
+	if err := os.Rename(tmpPath, pathFile); err != nil {
+		return errors.New(e + "while replacing " + pathFile + " " + err.Error())
+	}
 	return nil
 }
@@ -2,58 +2,44 @@ package assetmin
 
 import (
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
 
 	"github.com/tinywasm/font"
 )
 
-// copyDeclaredFonts copies the four faces of d from RootDir/<Dir()> into OutputDir.
+// copyDeclaredFonts copies the four faces of d from RootDir/<Dir()> into the OutputSink.
 // Skips a face when the destination exists and is not older than the source.
 // A missing source face is an error that names the file.
 func (c *AssetMin) copyDeclaredFonts(d font.Declaration) error {
 	if d.Family() == "" {
 		return nil
 	}
-	if err := os.MkdirAll(c.OutputDir, 0755); err != nil {
-		return err
-	}
 	for s := font.Regular; s <= font.BoldItalic; s++ {
 		name := d.Family().Face(s) + ".ttf"
 		src := filepath.Join(c.RootDir, d.Dir(), name)
-		dst := filepath.Join(c.OutputDir, name)
-		if err := copyFileIfStale(src, dst); err != nil {
+		if err := copyFileIfStale(c.OutputSink, src, name); err != nil {
 			return fmt.Errorf("font face %s: %w", name, err)
 		}
 	}
 	return nil
 }
 
-func copyFileIfStale(src, dst string) error {
+func copyFileIfStale(sink OutputSink, src, dst string) error {
 	srcInfo, err := os.Stat(src)
 	if err != nil {
 		return err
 	}
-	if dstInfo, err := os.Stat(dst); err == nil {
+	if dstInfo, err := sink.Stat(dst); err == nil {
 		if !srcInfo.ModTime().After(dstInfo.ModTime()) {
 			return nil
 		}
 	}
-	in, err := os.Open(src)
+	content, err := os.ReadFile(src)
 	if err != nil {
 		return err
 	}
-	defer in.Close()
-	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
-	if err != nil {
-		return err
-	}
-	if _, err := io.Copy(out, in); err != nil {
-		out.Close()
-		return err
-	}
-	return out.Close()
+	return sink.WriteFile(dst, content)
 }
 
 // fontOutputPaths returns the four destination paths for the current root fonts, or nil.
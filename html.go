@@ -19,6 +19,13 @@ func (h *htmlHandler) generateStylesheetLink() []byte {
 	return []byte(`<link rel="stylesheet" href="` + h.cssURL + `" type="text/css" />`)
 }
 
+// generateAsyncStylesheetLink returns HTML tags that load the CSS stylesheet
+// without blocking render, with a <noscript> fallback to the regular link.
+func (h *htmlHandler) generateAsyncStylesheetLink() []byte {
+	return []byte(`<link rel="preload" href="` + h.cssURL + `" as="style" onload="this.onload=null;this.rel='stylesheet'">` +
+		`<noscript>` + string(h.generateStylesheetLink()) + `</noscript>`)
+}
+
 // generateJavaScriptTag returns HTML script tag for a JavaScript file
 func (h *htmlHandler) generateJavaScriptTag() []byte {
 	return []byte(`<script src="` + h.jsURL + `" type="text/javascript"></script>`)
@@ -26,6 +33,10 @@ func (h *htmlHandler) generateJavaScriptTag() []byte {
 
 // NewHtmlHandler creates an HTML asset handler using the provided output filename
 func NewHtmlHandler(ac *Config, outputName, cssURL, jsURL, faviconURL string) *asset {
+	return newHtmlHandler(ac, outputName, cssURL, jsURL, faviconURL).asset
+}
+
+func newHtmlHandler(ac *Config, outputName, cssURL, jsURL, faviconURL string) *htmlHandler {
 	af := newAssetFile(outputName, "text/html", ac, nil)
 
 	hh := &htmlHandler{
@@ -71,7 +82,7 @@ func NewHtmlHandler(ac *Config, outputName, cssURL, jsURL, faviconURL string) *a
 </html>`),
 	})
 
-	return af
+	return hh
 }
 
 // parseExistingHtmlContent analiza un archivo HTML existente para identificar
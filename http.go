@@ -17,6 +17,22 @@ func (c *AssetMin) RegisterRoutes(r router.Router) {
 	r.PublicAsset(c.mainJsHandler.GetURLPath(), c.serveAsset(c.mainJsHandler))
 	r.PublicAsset(c.faviconSvgHandler.GetURLPath(), c.serveAsset(c.faviconSvgHandler))
 
+	// Source maps are a development aid: only served in DevMode.
+	if c.SourceMaps && c.DevMode {
+		r.PublicAsset(c.mainStyleCssHandler.GetURLPath()+".map", c.serveSourceMap(c.mainStyleCssHandler))
+		r.PublicAsset(c.mainJsHandler.GetURLPath()+".map", c.serveSourceMap(c.mainJsHandler))
+	}
+
+	// Diagnostics expose module names and paths: development only.
+	if c.ModulesPath != "" && c.DevMode {
+		r.Get(c.ModulesPath, c.serveModules).Public()
+	}
+
+	// Container probes have no identity either.
+	if c.HealthPath != "" {
+		r.Get(c.HealthPath, c.serveHealth).Public()
+	}
+
 	// Standalone JS assets
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -27,6 +43,9 @@ func (c *AssetMin) RegisterRoutes(r router.Router) {
 
 func (c *AssetMin) serveAsset(asset *asset) router.HandlerFunc {
 	return func(ctx router.Context) {
+		if !c.admit(ctx, asset) {
+			return
+		}
 		content, err := asset.GetMinifiedContent(c.min)
 		if err != nil {
 			ctx.WriteStatus(500)
@@ -35,19 +54,21 @@ func (c *AssetMin) serveAsset(asset *asset) router.HandlerFunc {
 		}
 
 		ctx.SetHeader("Content-Type", asset.mediatype)
-
-		// Robust check for HTML/JS regardless of charset
-		isDevMutableText := c.DevMode && strings.Contains(asset.mediatype, "text/")
-		if isDevMutableText ||
-			strings.Contains(asset.mediatype, "text/html") ||
-			strings.Contains(asset.mediatype, "application/javascript") ||
-			strings.Contains(asset.mediatype, "text/javascript") {
-			ctx.SetHeader("Cache-Control", "no-cache, no-store, must-revalidate")
-		} else {
-			// Production or non-text assets (images, fonts, etc.): Strong cache
-			ctx.SetHeader("Cache-Control", "public, max-age=31536000, immutable")
-		}
-
+		ctx.SetHeader("Cache-Control", cacheControl(asset.mediatype, c.DevMode))
 		ctx.Write(content)
 	}
 }
+
+// cacheControl is the Cache-Control policy for an asset of the given media type.
+func cacheControl(mediatype string, devMode bool) string {
+	// Robust check for HTML/JS regardless of charset
+	isDevMutableText := devMode && strings.Contains(mediatype, "text/")
+	if isDevMutableText ||
+		strings.Contains(mediatype, "text/html") ||
+		strings.Contains(mediatype, "application/javascript") ||
+		strings.Contains(mediatype, "text/javascript") {
+		return "no-cache, no-store, must-revalidate"
+	}
+	// Production or non-text assets (images, fonts, etc.): Strong cache
+	return "public, max-age=31536000, immutable"
+}
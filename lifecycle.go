@@ -0,0 +1,96 @@
+package assetmin
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrClosed is returned by operations on an AssetMin after Close.
+var ErrClosed = errors.New("assetmin: closed")
+
+// SSRContextExtractor is an SSRExtractor whose extraction can be cancelled.
+// When the injected extractor implements it, the Context variants of loading
+// and reload (and Close) stop an extraction in progress; otherwise they only
+// stop between attempts.
+type SSRContextExtractor interface {
+	SSRExtractor
+	ExtractModuleContext(ctx context.Context, moduleDir string) (*SSRAssets, error)
+	ExtractAllContext(ctx context.Context) ([]*SSRAssets, error)
+}
+
+func extractAll(ctx context.Context, e SSRExtractor) ([]*SSRAssets, error) {
+	if ce, ok := e.(SSRContextExtractor); ok {
+		return ce.ExtractAllContext(ctx)
+	}
+	return e.ExtractAll()
+}
+
+func extractModule(ctx context.Context, e SSRExtractor, moduleDir string) (*SSRAssets, error) {
+	if ce, ok := e.(SSRContextExtractor); ok {
+		return ce.ExtractModuleContext(ctx, moduleDir)
+	}
+	return e.ExtractModule(moduleDir)
+}
+
+// lifetime derives a context from ctx that is also cancelled by Close.
+func (c *AssetMin) lifetime(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(c.closeCtx, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// sleepContext waits for d, or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// track registers a flush or event batch, which writes to the OutputSink
+// without holding c.mu, so Close waits for it: call the returned func when it
+// is done. After Close it returns ErrClosed.
+func (c *AssetMin) track() (func(), error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil, ErrClosed
+	}
+	c.writing.Add(1)
+	return c.writing.Done, nil
+}
+
+// Close cancels the SSR loads and reloads in progress and waits for them, and
+// for the flushes and event batches being applied, to return, up to ctx.
+// Afterwards events, loads, reloads and flushes are rejected with ErrClosed
+// and nothing else is written to the OutputSink. Closing twice only waits
+// again.
+func (c *AssetMin) Close(ctx context.Context) error {
+	c.mu.Lock()
+	c.closed = true
+	c.stopRescan()
+	c.stopEvents()
+	c.mu.Unlock()
+	c.closeAll()
+
+	done := make(chan struct{})
+	go func() {
+		c.ssrLoading.Wait()
+		c.writing.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,181 @@
+package assetmin
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+
+	"github.com/tinywasm/font"
+)
+
+// manifestFileName is the machine-readable description of the outputs that
+// FlushToDisk writes next to the assets.
+const manifestFileName = "asset-manifest.json"
+
+// Manifest describes every output of a build so deploy scripts, CDN uploaders
+// and template helpers can resolve assets without hard-coding their names.
+type Manifest struct {
+	Assets []ManifestEntry `json:"assets"` // sorted by Name
+}
+
+// ManifestEntry describes one emitted file.
+type ManifestEntry struct {
+	Name      string   `json:"name"`              // logical name, eg: style.css
+	Path      string   `json:"path"`              // slash-separated, relative to OutputDir
+	URL       string   `json:"url"`               // HTTP route; "" when not served (the inline sprite)
+	MediaType string   `json:"mediaType"`         // eg: text/css
+	Hash      string   `json:"hash"`              // hex SHA-256 of the content
+	Size      int64    `json:"size"`              // bytes
+	Modules   []string `json:"modules,omitempty"` // contributing modules or source files
+}
+
+// Lookup returns the entry with the given logical name.
+func (m *Manifest) Lookup(name string) (ManifestEntry, bool) {
+	i := sort.Search(len(m.Assets), func(i int) bool { return m.Assets[i].Name >= name })
+	if i < len(m.Assets) && m.Assets[i].Name == name {
+		return m.Assets[i], true
+	}
+	return ManifestEntry{}, false
+}
+
+// URL returns the URL of the asset with the given logical name, or "".
+func (m *Manifest) URL(name string) string {
+	e, _ := m.Lookup(name)
+	return e.URL
+}
+
+// ReadManifest loads the asset-manifest.json written by FlushToDisk into dir.
+func ReadManifest(dir string) (*Manifest, error) {
+	content, err := os.ReadFile(filepath.Join(dir, manifestFileName))
+	if err != nil {
+		return nil, err
+	}
+	return parseManifest(content)
+}
+
+func parseManifest(content []byte) (*Manifest, error) {
+	var m Manifest
+	if err := json.Unmarshal(content, &m); err != nil {
+		return nil, fmt.Errorf("%s: %w", manifestFileName, err)
+	}
+	return &m, nil
+}
+
+// Manifest describes the current outputs, as FlushToDisk would write them.
+func (c *AssetMin) Manifest() (*Manifest, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, a := range c.allAssets {
+		if err := a.RegenerateCache(c.activeMinifier()); err != nil {
+			return nil, err
+		}
+	}
+	return c.manifest()
+}
+
+// manifest builds the manifest from the cached outputs, the root fonts and the
+// processed images. It assumes the caller holds c.mu and caches are current.
+func (c *AssetMin) manifest() (*Manifest, error) {
+	m := &Manifest{}
+
+	for _, a := range c.emittedAssets() {
+		a.mu.RLock()
+		content := a.cachedMinified
+		var modules []string
+		for _, s := range a.segments() {
+			if s.source != "" && !slices.Contains(modules, s.source) {
+				modules = append(modules, s.source)
+			}
+		}
+		a.mu.RUnlock()
+		if a == c.spriteSvgHandler || a == c.indexHtmlHandler {
+			modules = append(modules, c.spriteModules()...)
+		}
+		m.Assets = append(m.Assets, ManifestEntry{
+			Name:      a.fileOutputName,
+			Path:      a.fileOutputName,
+			URL:       a.urlPath,
+			MediaType: a.mediatype,
+			Hash:      contentHash(content),
+			Size:      int64(len(content)),
+			Modules:   modules,
+		})
+	}
+
+	for _, f := range c.copiedFiles() {
+		content, err := os.ReadFile(f.src)
+		if err != nil {
+			if f.optional && os.IsNotExist(err) {
+				continue // image not produced yet
+			}
+			return nil, err
+		}
+		m.Assets = append(m.Assets, ManifestEntry{
+			Name:      f.name,
+			Path:      f.name,
+			URL:       path.Join("/", c.AssetsURLPrefix, f.name),
+			MediaType: mime.TypeByExtension(filepath.Ext(f.name)),
+			Hash:      contentHash(content),
+			Size:      int64(len(content)),
+		})
+	}
+
+	sort.Slice(m.Assets, func(i, j int) bool { return m.Assets[i].Name < m.Assets[j].Name })
+	return m, nil
+}
+
+// copiedFile is an output AssetMin does not generate: a root font face, copied
+// from RootDir, or an image written into OutputDir by the ImageProcessor.
+type copiedFile struct {
+	name     string // slash-separated, relative to OutputDir
+	src      string // where the content is read from
+	optional bool   // images may not be produced yet
+}
+
+// copiedFiles lists the root font faces, then the processed images.
+func (c *AssetMin) copiedFiles() []copiedFile {
+	var out []copiedFile
+	c.fontsMu.RLock()
+	d := c.fonts
+	c.fontsMu.RUnlock()
+	if d.Family() != "" {
+		for s := font.Regular; s <= font.BoldItalic; s++ {
+			name := d.Family().Face(s) + ".ttf"
+			out = append(out, copiedFile{name: name, src: filepath.Join(c.RootDir, d.Dir(), name)})
+		}
+	}
+	if c.imageProcessor != nil {
+		for _, src := range c.imageProcessor.UnobservedFiles() {
+			rel := filepath.Base(src)
+			if r, err := filepath.Rel(c.OutputDir, src); err == nil && filepath.IsLocal(r) {
+				rel = r
+			}
+			out = append(out, copiedFile{name: filepath.ToSlash(rel), src: src, optional: true})
+		}
+	}
+	return out
+}
+
+// spriteModules returns the modules contributing icons to the sprite, sorted.
+func (c *AssetMin) spriteModules() []string {
+	c.spriteMu.RLock()
+	defer c.spriteMu.RUnlock()
+	out := make([]string, 0, len(c.moduleSprites))
+	for name := range c.moduleSprites {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func contentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,185 @@
+package assetmin
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/tinywasm/router"
+)
+
+// ModuleStatus describes one SSR module routed into the outputs by
+// LoadSSRModules or ReloadSSRModule.
+type ModuleStatus struct {
+	Name        string         `json:"name"`
+	Dir         string         `json:"dir,omitempty"` // module directory, when known (SSRAssets.Dir or a reload)
+	IsRoot      bool           `json:"isRoot"`
+	IsFramework bool           `json:"isFramework"`
+	Kinds       []string       `json:"kinds"`                // contributed asset kinds, sorted: css, fonts, html, icons, js, rootCSS, standalone
+	Sizes       map[string]int `json:"sizes"`                // bytes contributed per kind (fonts: none)
+	Standalone  []string       `json:"standalone,omitempty"` // standalone scripts, eg: sw.js
+	Fonts       string         `json:"fonts,omitempty"`      // declared font family
+	LoadedAt    time.Time      `json:"loadedAt"`             // last successful extraction; zero when none
+	Error       string         `json:"error,omitempty"`      // last extraction error; cleared by a success
+	ErrorAt     time.Time      `json:"errorAt"`
+}
+
+// recordModule records a module routed by routeAssets. It assumes the
+// caller holds c.mu.
+func (c *AssetMin) recordModule(a *SSRAssets, dir string, isRoot, isFramework bool) {
+	m := c.moduleEntry(a.ModuleName)
+	if dir == "" {
+		dir = a.Dir
+	}
+	if dir != "" {
+		m.Dir = dir
+	}
+	m.IsRoot, m.IsFramework = isRoot, isFramework
+	m.Sizes = make(map[string]int)
+	m.Standalone = nil
+	m.Fonts = ""
+
+	if a.CSS != "" {
+		m.Sizes["css"] = len(a.CSS)
+	}
+	if a.RootCSS != "" && (isRoot || isFramework) {
+		m.Sizes["rootCSS"] = len(a.RootCSS)
+	}
+	for _, s := range a.JS {
+		if s.Name == "" {
+			m.Sizes["js"] += len(s.Content)
+		} else {
+			m.Sizes["standalone"] += len(s.Content)
+			m.Standalone = append(m.Standalone, s.Name)
+		}
+	}
+	if a.HTML != "" {
+		m.Sizes["html"] = len(a.HTML)
+	}
+	if a.Icons != nil {
+		for _, def := range a.Icons.Icons() {
+			m.Sizes["icons"] += len(def.Body)
+		}
+	}
+	if a.Fonts.Family() != "" && isRoot {
+		m.Fonts = string(a.Fonts.Family())
+	}
+
+	m.Kinds = m.Kinds[:0]
+	for kind := range m.Sizes {
+		m.Kinds = append(m.Kinds, kind)
+	}
+	if m.Fonts != "" {
+		m.Kinds = append(m.Kinds, "fonts")
+	}
+	sort.Strings(m.Kinds)
+	m.LoadedAt = time.Now()
+	m.Error = ""
+	m.ErrorAt = time.Time{}
+}
+
+// moduleKinds returns the kinds name contributed at its last extraction.
+// It assumes the caller holds c.mu.
+func (c *AssetMin) moduleKinds(name string) []string {
+	if m, ok := c.modules[name]; ok {
+		return slices.Clone(m.Kinds)
+	}
+	return nil
+}
+
+// kindExtensions maps contribution kinds to the extensions refreshAsset takes.
+func kindExtensions(kinds []string) map[string]bool {
+	exts := make(map[string]bool, len(kinds))
+	for _, kind := range kinds {
+		switch kind {
+		case "css", "rootCSS", "fonts":
+			exts[".css"] = true
+		case "js", "standalone":
+			exts[".js"] = true
+		case "html":
+			exts[".html"] = true
+		case "icons":
+			exts[".svg"] = true
+		}
+	}
+	return exts
+}
+
+// recordModuleError records a failed extraction. The module is taken from a
+// *ModuleError in err, else from name, else from the entry loaded from dir;
+// an error no module can be found for is not recorded. It assumes the caller
+// holds c.mu.
+func (c *AssetMin) recordModuleError(name, dir string, err error) {
+	var me *ModuleError
+	if errors.As(err, &me) {
+		name, err = me.Module, me.Err // the entry already names the module
+	}
+	if name == "" {
+		for _, m := range c.modules {
+			if dir != "" && m.Dir == dir {
+				name = m.Name
+				break
+			}
+		}
+	}
+	if name == "" {
+		// No module was loaded from dir: there is no entry to attach the
+		// error to, and the caller returns it.
+		return
+	}
+	m := c.moduleEntry(name)
+	if dir != "" {
+		m.Dir = dir
+	}
+	m.Error = err.Error()
+	m.ErrorAt = time.Now()
+}
+
+// moduleEntry returns the registry entry of name, created on first use.
+// It assumes the caller holds c.mu.
+func (c *AssetMin) moduleEntry(name string) *ModuleStatus {
+	if c.modules == nil {
+		c.modules = make(map[string]*ModuleStatus)
+	}
+	m, ok := c.modules[name]
+	if !ok {
+		m = &ModuleStatus{Name: name, Kinds: []string{}, Sizes: map[string]int{}}
+		c.modules[name] = m
+	}
+	return m
+}
+
+// Modules returns the status of every SSR module routed so far, sorted by
+// name. Entries are copies.
+func (c *AssetMin) Modules() []ModuleStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]ModuleStatus, 0, len(c.modules))
+	for _, m := range c.modules {
+		s := *m
+		s.Kinds = append([]string{}, m.Kinds...)
+		s.Standalone = append([]string(nil), m.Standalone...)
+		s.Sizes = make(map[string]int, len(m.Sizes))
+		for k, v := range m.Sizes {
+			s.Sizes[k] = v
+		}
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// serveModules answers the Config.ModulesPath route with Modules() as JSON.
+func (c *AssetMin) serveModules(ctx router.Context) {
+	out, err := json.MarshalIndent(c.Modules(), "", "  ")
+	if err != nil {
+		ctx.WriteStatus(500)
+		ctx.Write([]byte(err.Error()))
+		return
+	}
+	ctx.SetHeader("Content-Type", "application/json")
+	ctx.SetHeader("Cache-Control", "no-cache, no-store, must-revalidate")
+	ctx.Write(out)
+}
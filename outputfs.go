@@ -0,0 +1,264 @@
+package assetmin
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FS returns a read-only view of the current outputs, as Flush would write
+// them: every emitted asset (bundles, sprite, favicon, index, standalone
+// scripts), the source maps and size report when enabled, asset-manifest.json,
+// the root fonts and the processed images. Files carry their size and the
+// time their content last changed. Every Open reflects the current state:
+// the view keeps a snapshot and takes a new one only once an output or a
+// copied file changed, so walking it does not rebuild everything per file.
+//
+// The view works with http.FileServer(http.FS(...)), fs.WalkDir,
+// testing/fstest and template loaders without touching disk.
+func (c *AssetMin) FS() fs.FS {
+	return &outputFS{c: c}
+}
+
+// outputFile is one file of the FS view.
+type outputFile struct {
+	content []byte
+	modTime time.Time
+}
+
+// outputVersion tells two states of one output file apart.
+type outputVersion struct {
+	size    int64
+	modTime time.Time
+}
+
+// outputStamp identifies the state an output snapshot was taken from: the
+// version of every emitted asset and copied file, by name.
+type outputStamp map[string]outputVersion
+
+// outputFiles snapshots the current outputs by name, with their stamp.
+func (c *AssetMin) outputFiles() (map[string]outputFile, outputStamp, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, a := range c.allAssets {
+		if err := a.RegenerateCache(c.activeMinifier()); err != nil {
+			return nil, nil, err
+		}
+	}
+	stamp, _ := c.outputStamp()
+
+	files := make(map[string]outputFile)
+	var latest time.Time
+	for _, a := range c.emittedAssets() {
+		a.mu.RLock()
+		f := outputFile{content: a.cachedMinified, modTime: a.modTime}
+		a.mu.RUnlock()
+		files[c.sinkName(a.outputPath)] = f
+		if f.modTime.After(latest) {
+			latest = f.modTime
+		}
+	}
+	for _, a := range c.sourceMapOutputs() {
+		a.mu.RLock()
+		files[c.sinkName(a.outputPath)+".map"] = outputFile{content: a.cachedMap, modTime: a.modTime}
+		a.mu.RUnlock()
+	}
+
+	if c.WriteSizeReport {
+		report, err := c.sizeReport()
+		if err != nil {
+			return nil, nil, err
+		}
+		content, err := sizeReportJSON(report)
+		if err != nil {
+			return nil, nil, err
+		}
+		files[sizeReportFileName] = outputFile{content: content, modTime: latest}
+	}
+
+	manifest, err := c.manifest()
+	if err != nil {
+		return nil, nil, err
+	}
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, nil, err
+	}
+	files[manifestFileName] = outputFile{content: content, modTime: latest}
+
+	for _, f := range c.copiedFiles() {
+		info, err := os.Stat(f.src)
+		if err != nil {
+			if f.optional && os.IsNotExist(err) {
+				continue
+			}
+			return nil, nil, err
+		}
+		content, err := os.ReadFile(f.src)
+		if err != nil {
+			return nil, nil, err
+		}
+		files[f.name] = outputFile{content: content, modTime: info.ModTime()}
+	}
+	return files, stamp, nil
+}
+
+// outputStamp returns the stamp of the current outputs, without building
+// them: ok is false when an emitted asset needs a rebuild. It assumes the
+// caller holds c.mu.
+func (c *AssetMin) outputStamp() (stamp outputStamp, ok bool) {
+	stamp = make(outputStamp)
+	for _, a := range c.emittedAssets() {
+		a.mu.RLock()
+		valid := a.cacheValid
+		stamp[c.sinkName(a.outputPath)] = outputVersion{size: int64(len(a.cachedMinified)), modTime: a.modTime}
+		a.mu.RUnlock()
+		if !valid {
+			return nil, false
+		}
+	}
+	for _, f := range c.copiedFiles() {
+		if info, err := os.Stat(f.src); err == nil {
+			stamp[f.name] = outputVersion{size: info.Size(), modTime: info.ModTime()}
+		}
+	}
+	return stamp, true
+}
+
+// equal reports whether both stamps record the same versions.
+func (s outputStamp) equal(o outputStamp) bool {
+	return maps.EqualFunc(s, o, func(a, b outputVersion) bool {
+		return a.size == b.size && a.modTime.Equal(b.modTime)
+	})
+}
+
+// outputFS is the FS view. It serves every Open from its last snapshot while
+// the outputs it was taken from are unchanged.
+type outputFS struct {
+	c     *AssetMin
+	mu    sync.Mutex
+	files map[string]outputFile
+	stamp outputStamp
+}
+
+// snapshot returns the current outputs, taking a new snapshot only when they
+// changed since the last one.
+func (fsys *outputFS) snapshot() (map[string]outputFile, error) {
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+	if fsys.stamp != nil {
+		fsys.c.mu.Lock()
+		stamp, ok := fsys.c.outputStamp()
+		fsys.c.mu.Unlock()
+		if ok && stamp.equal(fsys.stamp) {
+			return fsys.files, nil
+		}
+	}
+	files, stamp, err := fsys.c.outputFiles()
+	if err != nil {
+		return nil, err
+	}
+	fsys.files, fsys.stamp = files, stamp
+	return files, nil
+}
+
+func (fsys *outputFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	files, err := fsys.snapshot()
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	if f, ok := files[name]; ok {
+		info := fileInfo{name: path.Base(name), size: int64(len(f.content)), modTime: f.modTime}
+		return &openFile{Reader: bytes.NewReader(f.content), info: info}, nil
+	}
+
+	// A directory: "." or the parent of at least one file.
+	prefix := name + "/"
+	if name == "." {
+		prefix = ""
+	}
+	entries := make(map[string]fs.DirEntry)
+	var dirTime time.Time
+	for n, f := range files {
+		rest, ok := strings.CutPrefix(n, prefix)
+		if !ok {
+			continue
+		}
+		if f.modTime.After(dirTime) {
+			dirTime = f.modTime
+		}
+		if sub, _, isDir := strings.Cut(rest, "/"); isDir {
+			// A directory's mod time is its newest file's, as when opened.
+			modTime := f.modTime
+			if e, ok := entries[sub]; ok {
+				if info, _ := e.Info(); info.ModTime().After(modTime) {
+					modTime = info.ModTime()
+				}
+			}
+			entries[sub] = fs.FileInfoToDirEntry(fileInfo{name: sub, modTime: modTime, dir: true})
+		} else {
+			entries[rest] = fs.FileInfoToDirEntry(fileInfo{name: rest, size: int64(len(f.content)), modTime: f.modTime})
+		}
+	}
+	if len(entries) == 0 && name != "." {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	list := make([]fs.DirEntry, 0, len(entries))
+	for _, e := range entries {
+		list = append(list, e)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name() < list[j].Name() })
+	return &openDir{info: fileInfo{name: path.Base(name), modTime: dirTime, dir: true}, entries: list}, nil
+}
+
+// openFile is an output opened from the FS view; it supports Seek so
+// http.FileServer can serve ranges.
+type openFile struct {
+	*bytes.Reader
+	info fileInfo
+}
+
+func (f *openFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *openFile) Close() error               { return nil }
+
+type openDir struct {
+	info    fileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *openDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *openDir) Close() error               { return nil }
+
+func (d *openDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: fs.ErrInvalid}
+}
+
+func (d *openDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(rest) {
+		n = len(rest)
+	}
+	d.offset += n
+	return rest[:n], nil
+}
@@ -0,0 +1,154 @@
+package assetmin
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"sort"
+)
+
+// FlushResult lists what a flush did to OutputDir. Paths are output paths
+// (OutputDir joined with the file name), sorted.
+type FlushResult struct {
+	Written   []string // content changed (or file missing) and was written
+	Unchanged []string // the file already held the content; not touched
+	Removed   []string // owned files no longer produced
+}
+
+// writeOutput writes content to path unless the file already holds it, as
+// known from the hash of the last write or, after a restart, from the file
+// itself. Skipped files keep their mtime, so downstream watchers, rsync and
+// CDN invalidations are not retriggered. It reports whether it wrote.
+func (c *AssetMin) writeOutput(path string, content []byte) (bool, error) {
+	hash := contentHash(content)
+	c.outputMu.Lock()
+	last, known := c.outputHashes[path]
+	c.outputMu.Unlock()
+
+	name := c.sinkName(path)
+	unchanged := false
+	if known && last == hash {
+		info, err := c.OutputSink.Stat(name)
+		unchanged = err == nil && info.Size() == int64(len(content))
+	} else if !known {
+		onDisk, err := c.OutputSink.ReadFile(name)
+		unchanged = err == nil && bytes.Equal(onDisk, content)
+	}
+	if !unchanged {
+		if err := c.OutputSink.WriteFile(name, content); err != nil {
+			return false, err
+		}
+	}
+
+	c.outputMu.Lock()
+	if c.outputHashes == nil {
+		c.outputHashes = make(map[string]string)
+	}
+	c.outputHashes[path] = hash
+	c.outputMu.Unlock()
+	return !unchanged, nil
+}
+
+// forgetOutput drops the cached hash of a removed file.
+func (c *AssetMin) forgetOutput(path string) {
+	c.outputMu.Lock()
+	delete(c.outputHashes, path)
+	c.outputMu.Unlock()
+}
+
+// emitted reports whether the asset produces an output file. A standalone
+// script every module stopped emitting keeps its handler (and route) but has
+// no file. It assumes the caller holds c.mu.
+func (c *AssetMin) emitted(a *asset) bool {
+	if c.standaloneJS[a.fileOutputName] != a {
+		return true
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return len(a.contentOpen)+len(a.contentMiddle)+len(a.contentClose) > 0
+}
+
+// emittedAssets returns the assets that produce an output file.
+// It assumes the caller holds c.mu.
+func (c *AssetMin) emittedAssets() []*asset {
+	out := make([]*asset, 0, len(c.allAssets))
+	for _, a := range c.allAssets {
+		if c.emitted(a) {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// ownedExtensions are the kinds of file AssetMin writes through the
+// OutputSink: bundles, standalone scripts, the sprite and favicon, the index,
+// the manifest and size report, and the root font faces. The processed images
+// the manifest also lists are written by the ImageProcessor, never owned.
+var ownedExtensions = []string{".css", ".js", ".html", ".svg", ".json", ".ttf"}
+
+// ownedOutputs returns the record of files in the OutputSink written by AssetMin.
+// The first call seeds it from the asset-manifest.json of a previous run, so
+// outputs a restarted process no longer produces are still cleaned up.
+// It assumes the caller holds c.mu.
+func (c *AssetMin) ownedOutputs() map[string]bool {
+	if c.ownedFiles != nil {
+		return c.ownedFiles
+	}
+	c.ownedFiles = make(map[string]bool)
+	content, err := c.OutputSink.ReadFile(manifestFileName)
+	if err != nil {
+		return c.ownedFiles
+	}
+	prev, err := parseManifest(content)
+	if err != nil {
+		return c.ownedFiles
+	}
+	c.ownedFiles[filepath.Join(c.OutputDir, manifestFileName)] = true
+	c.ownedFiles[filepath.Join(c.OutputDir, sizeReportFileName)] = true
+	for _, e := range prev.Assets {
+		rel := filepath.FromSlash(e.Path)
+		if !filepath.IsLocal(rel) || !slices.Contains(ownedExtensions, filepath.Ext(rel)) {
+			continue // never touch files outside OutputDir, nor images
+		}
+		path := filepath.Join(c.OutputDir, rel)
+		c.ownedFiles[path] = true
+		c.ownedFiles[path+".map"] = true
+	}
+	return c.ownedFiles
+}
+
+// removeOrphans deletes the owned files that are not in current, then records
+// current as the owned set. It returns the removed paths, sorted.
+// It assumes the caller holds c.mu.
+func (c *AssetMin) removeOrphans(current map[string]bool) ([]string, error) {
+	var removed []string
+	var errs []error
+	for path := range c.ownedOutputs() {
+		if current[path] {
+			continue
+		}
+		c.forgetOutput(path)
+		switch err := c.OutputSink.Remove(c.sinkName(path)); {
+		case err == nil:
+			removed = append(removed, path)
+		case !errors.Is(err, fs.ErrNotExist):
+			errs = append(errs, err)
+		}
+	}
+	c.ownedFiles = current
+	sort.Strings(removed)
+	return removed, errors.Join(errs...)
+}
+
+// removeOutput deletes the file of an asset that no longer emits one.
+// It assumes the caller holds c.mu.
+func (c *AssetMin) removeOutput(a *asset) error {
+	delete(c.ownedFiles, a.outputPath)
+	c.forgetOutput(a.outputPath)
+	if err := c.OutputSink.Remove(c.sinkName(a.outputPath)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package assetmin
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// plainDir returns the Config.PlainAssetDirs entry path lies in, made
+// absolute against RootDir, or "" when it lies in none. Files there are plain
+// assets even in SSR mode: NewFileEvent reads them through
+// UpdateFileContentInMemory instead of re-extracting their directory.
+func (c *AssetMin) plainDir(path string) string {
+	for _, dir := range c.plainDirs() {
+		if pathUnder(path, dir) {
+			return dir
+		}
+	}
+	return ""
+}
+
+// touchesPlainDir reports whether dir is or lies in a Config.PlainAssetDirs
+// entry, or holds one: a directory event there changes plain files even in
+// SSR mode.
+func (c *AssetMin) touchesPlainDir(dir string) bool {
+	for _, plain := range c.plainDirs() {
+		if pathUnder(dir, plain) || pathUnder(plain, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// plainDirs returns Config.PlainAssetDirs made absolute against RootDir.
+func (c *AssetMin) plainDirs() []string {
+	dirs := make([]string, 0, len(c.PlainAssetDirs))
+	for _, dir := range c.PlainAssetDirs {
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(c.RootDir, dir)
+		}
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
+
+// contentKey is the ContentFile path a plain file is stored under. Below
+// Config.PlainAssetDirs it is "./" plus the slash path relative to RootDir:
+// in the shared slots entries sort by path and SSR modules by name, so plain
+// files always come before every module (and the root project, in the close
+// slot, after both), wherever the project is checked out.
+func (c *AssetMin) contentKey(path string) string {
+	if c.plainDir(path) == "" {
+		return path
+	}
+	if key := c.relativeKey(path); key != "" {
+		return key
+	}
+	return path
+}
+
+// relativeKey returns path in the "./" form of contentKey, or "" when path
+// does not lie below RootDir.
+func (c *AssetMin) relativeKey(path string) string {
+	rel, err := filepath.Rel(c.RootDir, path)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return ""
+	}
+	return "./" + filepath.ToSlash(rel)
+}
@@ -0,0 +1,99 @@
+package assetmin
+
+import "strings"
+
+// CSSSavings is the effect of unused-CSS pruning on one contributor of style.css.
+type CSSSavings struct {
+	Module string // ContentFile.Path: module name or source file
+	Before int    // bytes contributed before pruning
+	After  int    // bytes left after pruning
+}
+
+// pruneCSS drops the rules of css whose selectors are not used.
+func pruneCSS(css string, used *selectorSet) string {
+	var b strings.Builder
+	writeCSSRules(&b, filterCSSRules(parseCSSRules(css), used.matches))
+	return b.String()
+}
+
+// usedSelectors collects the element names, classes and ids referenced by the
+// index HTML, the JS bundle and Config.CSSSafelist.
+func (c *AssetMin) usedSelectors() *selectorSet {
+	used := newSelectorSet()
+	for _, safe := range c.CSSSafelist {
+		used.safelist = append(used.safelist, strings.TrimLeft(safe, ".#"))
+	}
+
+	c.indexHtmlHandler.mu.RLock()
+	for _, slot := range [][]*ContentFile{c.indexHtmlHandler.contentOpen, c.indexHtmlHandler.contentMiddle, c.indexHtmlHandler.contentClose} {
+		for _, f := range slot {
+			used.addHTML(string(f.Content))
+		}
+	}
+	c.indexHtmlHandler.mu.RUnlock()
+
+	c.mainJsHandler.mu.RLock()
+	for _, slot := range [][]*ContentFile{c.mainJsHandler.contentOpen, c.mainJsHandler.contentMiddle, c.mainJsHandler.contentClose} {
+		for _, f := range slot {
+			used.addScript(string(f.Content))
+		}
+	}
+	c.mainJsHandler.mu.RUnlock()
+
+	return used
+}
+
+// pruning reports whether unused-CSS pruning applies: opt-in, production only.
+func (c *AssetMin) pruning() bool {
+	return c.PruneCSS && !c.DevMode
+}
+
+// enablePruneCSS wires style.css to drop unused rules on every regeneration.
+// The HTML and JS bundles list the stylesheet as a source dependent: a new
+// class in either may bring back a rule that was pruned. Only their sources
+// count, so the critical CSS inlined into the HTML never loops back here.
+func (c *AssetMin) enablePruneCSS() {
+	c.indexHtmlHandler.sourceDependents = append(c.indexHtmlHandler.sourceDependents, c.mainStyleCssHandler)
+	c.mainJsHandler.sourceDependents = append(c.mainJsHandler.sourceDependents, c.mainStyleCssHandler)
+	c.mainStyleCssHandler.transform = func() func([]byte) []byte {
+		if !c.pruning() {
+			return nil
+		}
+		// Collected before the stylesheet locks: the HTML and JS handlers are
+		// never read while the CSS handler is held.
+		used := c.usedSelectors()
+		return func(css []byte) []byte {
+			return []byte(pruneCSS(string(css), used))
+		}
+	}
+}
+
+// CSSPruneReport returns, for every contributor of style.css, its size before
+// and after pruning against the current HTML, JS and safelist. Contributors
+// are listed in bundle order. It returns nil unless pruning is active.
+func (c *AssetMin) CSSPruneReport() []CSSSavings {
+	if !c.pruning() {
+		return nil
+	}
+	used := c.usedSelectors()
+	// Before is measured through the same parse/serialize pass as After, so
+	// comments and formatting never count as savings.
+	all := newSelectorSet()
+	all.safelist = []string{"*"}
+
+	h := c.mainStyleCssHandler
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var out []CSSSavings
+	for _, slot := range [][]*ContentFile{h.contentOpen, h.contentMiddle, h.contentClose} {
+		for _, f := range slot {
+			out = append(out, CSSSavings{
+				Module: f.Path,
+				Before: len(pruneCSS(string(f.Content), all)),
+				After:  len(pruneCSS(string(f.Content), used)),
+			})
+		}
+	}
+	return out
+}
@@ -0,0 +1,159 @@
+package assetmin
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/tinywasm/router"
+)
+
+// LoadState is the readiness of the SSR module load.
+type LoadState int
+
+const (
+	LoadReady   LoadState = iota // no load running; the last one succeeded (or none ran)
+	LoadLoading                  // the first LoadSSRModules is pending or in progress
+	LoadFailed                   // the last load failed; see SSRLoadError
+)
+
+func (s LoadState) String() string {
+	switch s {
+	case LoadLoading:
+		return "loading"
+	case LoadFailed:
+		return "failed"
+	default:
+		return "ready"
+	}
+}
+
+// LoadGate selects how asset routes answer while an SSR load is in progress.
+type LoadGate int
+
+const (
+	LoadGateOff    LoadGate = iota // serve whatever is loaded so far
+	LoadGateBlock                  // wait for the load up to LoadGateTimeout, then answer 503
+	LoadGateReject                 // answer 503 with Retry-After at once
+)
+
+// defaultLoadGateTimeout is the LoadGateBlock wait when LoadGateTimeout is zero.
+const defaultLoadGateTimeout = 10 * time.Second
+
+// retryAfterSeconds is the Retry-After of a 503 sent while loading.
+const retryAfterSeconds = "1"
+
+// Readiness reports the state of the SSR module load and, when it failed,
+// its error. Unlike WaitForSSRLoad it never blocks. With an SSR extractor set
+// it reports LoadLoading until the first load completes, also before that load
+// is scheduled. A later load (rescan, retry, reload) keeps the state of the
+// last completed load, whose outputs are still served meanwhile.
+func (c *AssetMin) Readiness() (LoadState, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch {
+	case !c.firstLoadDone && (c.ssrPending > 0 || c.ssrExtractor != nil):
+		return LoadLoading, nil
+	case c.ssrLoadErr != nil:
+		return LoadFailed, c.ssrLoadErr
+	default:
+		return LoadReady, nil
+	}
+}
+
+// startSSRLoad records a load in progress. It assumes the caller holds c.mu.
+func (c *AssetMin) startSSRLoad() {
+	if c.ssrPending == 0 {
+		c.ssrDone = make(chan struct{})
+	}
+	c.ssrPending++
+}
+
+// finishSSRLoad records the end of a load, once its outputs are rebuilt.
+func (c *AssetMin) finishSSRLoad() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ssrPending--
+	if c.ssrPending == 0 {
+		c.firstLoadDone = true
+		close(c.ssrDone)
+	}
+}
+
+// loadDone returns a channel closed once no load is in progress.
+func (c *AssetMin) loadDone() <-chan struct{} {
+	return c.loadDoneIf(true)
+}
+
+// firstLoadWait returns a channel closed once the first load completed;
+// it is already closed when that happened or no load runs.
+func (c *AssetMin) firstLoadWait() <-chan struct{} {
+	return c.loadDoneIf(false)
+}
+
+func (c *AssetMin) loadDoneIf(afterFirst bool) <-chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ssrPending == 0 || (c.firstLoadDone && !afterFirst) {
+		done := make(chan struct{})
+		close(done)
+		return done
+	}
+	return c.ssrDone
+}
+
+// admit applies Config.LoadGate to a request for a during the first load. It
+// reports whether the asset may be served; otherwise it has already answered
+// 503. A warm-started output is complete, so it is always served.
+func (c *AssetMin) admit(ctx router.Context, a *asset) bool {
+	if c.LoadGate == LoadGateOff || a.isHeld() {
+		return true
+	}
+	done := c.firstLoadWait()
+	select {
+	case <-done:
+		return true
+	default:
+	}
+	if c.LoadGate == LoadGateBlock {
+		timeout := c.LoadGateTimeout
+		if timeout <= 0 {
+			timeout = defaultLoadGateTimeout
+		}
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		select {
+		case <-done:
+			return true
+		case <-timer.C:
+		}
+	}
+	ctx.SetHeader("Retry-After", retryAfterSeconds)
+	ctx.WriteStatus(503)
+	ctx.Write([]byte("Assets are loading"))
+	return false
+}
+
+// serveHealth answers the Config.HealthPath probe: 200 when ready, 503 during
+// the first load or after a failed load, with the state as JSON.
+func (c *AssetMin) serveHealth(ctx router.Context) {
+	state, err := c.Readiness()
+	body := struct {
+		State string `json:"state"`
+		Error string `json:"error,omitempty"`
+	}{State: state.String()}
+	if err != nil {
+		body.Error = err.Error()
+	}
+	out, _ := json.Marshal(body)
+
+	ctx.SetHeader("Content-Type", "application/json")
+	ctx.SetHeader("Cache-Control", "no-store")
+	switch state {
+	case LoadLoading:
+		ctx.SetHeader("Retry-After", retryAfterSeconds)
+		ctx.WriteStatus(503)
+	case LoadFailed:
+		ctx.WriteStatus(503)
+	}
+	ctx.Write(out)
+}
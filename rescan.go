@@ -0,0 +1,100 @@
+package assetmin
+
+import "time"
+
+// goModuleFiles are the basenames whose change alters the module graph: a
+// dependency that ships components may appear, change or disappear.
+var goModuleFiles = []string{"go.mod", "go.sum", "go.work", "go.work.sum"}
+
+// rescanDebounce is how long scheduleRescan waits for the module files to
+// settle: `go get` rewrites go.mod and go.sum in quick succession.
+const rescanDebounce = 300 * time.Millisecond
+
+// scheduleRescan runs a full ScheduleSSRLoad once no call arrived for
+// rescanDebounce, then (when set) calls then after that load. Until it runs,
+// WaitForSSRLoad and Close wait for it as for a load.
+func (c *AssetMin) scheduleRescan(then func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	if then != nil {
+		c.rescanThen = then
+	}
+	if c.rescanTimer != nil && c.rescanTimer.Stop() {
+		c.rescanTimer.Reset(rescanDebounce)
+		return
+	}
+	c.ssrLoading.Add(1)
+	var timer *time.Timer
+	timer = time.AfterFunc(rescanDebounce, func() { c.rescan(&timer) })
+	c.rescanTimer = timer
+}
+
+// rescan runs the rescan of the timer held in *timer, read under c.mu. A
+// timer that fired while scheduleRescan was arming a newer one leaves the
+// pending timer, and the then set for it, to that newer one.
+func (c *AssetMin) rescan(timer **time.Timer) {
+	defer c.ssrLoading.Done()
+	c.mu.Lock()
+	var then func()
+	if c.rescanTimer == *timer {
+		then = c.rescanThen
+		c.rescanTimer, c.rescanThen = nil, nil
+	}
+	closed := c.closed
+	c.mu.Unlock()
+	if closed {
+		return
+	}
+
+	c.ScheduleSSRLoad()
+	if then != nil {
+		<-c.loadDone()
+		then()
+	}
+}
+
+// stopRescan cancels a pending rescan. It assumes the caller holds c.mu.
+func (c *AssetMin) stopRescan() {
+	if c.rescanTimer != nil && c.rescanTimer.Stop() {
+		c.rescanTimer = nil
+		c.rescanThen = nil
+		c.ssrLoading.Done()
+	}
+}
+
+// dropVanishedModules removes the registered modules a complete extraction no
+// longer returns, e.g. after a dependency was dropped from go.mod. It assumes
+// the caller holds c.mu.
+func (c *AssetMin) dropVanishedModules(extracted []*SSRAssets) {
+	present := make(map[string]bool, len(extracted))
+	for _, a := range extracted {
+		present[a.ModuleName] = true
+	}
+	for name := range c.modules {
+		if !present[name] {
+			c.removeSSRModule(name)
+		}
+	}
+}
+
+// freezeOutputs keeps every built output served as is while a load replaces
+// the slots, so no request sees a half-applied bundle; thawOutputs ends it.
+// It assumes the caller holds c.mu.
+func (c *AssetMin) freezeOutputs() []*asset {
+	var frozen []*asset
+	for _, a := range c.allAssets {
+		if a.freeze() {
+			frozen = append(frozen, a)
+		}
+	}
+	return frozen
+}
+
+func thawOutputs(frozen []*asset) {
+	for _, a := range frozen {
+		a.release()
+	}
+}
@@ -0,0 +1,34 @@
+package assetmin
+
+import (
+	"testing"
+	"time"
+)
+
+// The first timer fires while c.mu is held, and a second scheduleRescan is
+// already waiting for c.mu ahead of it: that call arms a new timer before the
+// fired one runs, and its then belongs to the new timer.
+func TestScheduleRescan_FiredTimerLeavesTheNewerOne(t *testing.T) {
+	c := NewAssetMin(&Config{OutputDir: t.TempDir()})
+	c.scheduleRescan(nil)
+
+	called := make(chan time.Time, 2)
+	c.mu.Lock()
+	go c.scheduleRescan(func() { called <- time.Now() })
+	time.Sleep(rescanDebounce + 100*time.Millisecond)
+	rearmed := time.Now()
+	c.mu.Unlock()
+
+	c.WaitForSSRLoad(5 * time.Second)
+	select {
+	case at := <-called:
+		if at.Sub(rearmed) < rescanDebounce/2 {
+			t.Errorf("then ran %v after the rearm, with the timer that had already fired", at.Sub(rearmed))
+		}
+	default:
+		t.Fatal("then never ran")
+	}
+	if len(called) != 0 {
+		t.Error("then ran twice")
+	}
+}
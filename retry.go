@@ -0,0 +1,197 @@
+package assetmin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// RetryPolicy controls how LoadSSRModules retries a failed ExtractAll. Zero
+// fields take the defaults.
+type RetryPolicy struct {
+	Attempts       int           // tries, including the first (default: 5)
+	InitialBackoff time.Duration // wait after the first failure (default: 200ms)
+	MaxBackoff     time.Duration // cap of the doubling wait (default: 3s)
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.Attempts <= 0 {
+		p.Attempts = 5
+	}
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = 200 * time.Millisecond
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = 3 * time.Second
+	}
+	return p
+}
+
+// backoff is the wait after the given number of failures (1 for the first).
+func (p RetryPolicy) backoff(failures int) time.Duration {
+	d := p.InitialBackoff
+	for i := 1; i < failures && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, p.MaxBackoff)
+}
+
+// FailureKind classifies a failed extraction.
+type FailureKind int
+
+const (
+	FailureTransient FailureKind = iota // eg: IO or toolchain hiccup; retried per RetryPolicy
+	FailureModule                       // one module is broken (eg: does not compile); retried when it changes
+	FailureCancelled                    // the load's context was cancelled or AssetMin closed
+)
+
+func (k FailureKind) String() string {
+	switch k {
+	case FailureModule:
+		return "module"
+	case FailureCancelled:
+		return "cancelled"
+	default:
+		return "transient"
+	}
+}
+
+// ModuleError is returned, possibly wrapped, by an SSRExtractor when one
+// module fails, eg: it does not compile. Retrying cannot fix it, so the load
+// fails at once and is retried on the next change of a module's sources. A
+// plain error holding the go command's "# import/path" line above the
+// compile errors of a package of a known module is taken as that module's
+// (see asModuleError).
+type ModuleError struct {
+	Module string // module path, eg: github.com/acme/ui
+	Err    error
+}
+
+func (e *ModuleError) Error() string { return "module " + e.Module + ": " + e.Err.Error() }
+func (e *ModuleError) Unwrap() error { return e.Err }
+
+// LoadAttempt is one ExtractAll call of an SSR load.
+type LoadAttempt struct {
+	Start    time.Time
+	Duration time.Duration
+	Err      error       // nil for a successful attempt
+	Kind     FailureKind // classification of Err
+	Module   string      // offending module when Kind is FailureModule
+}
+
+// LoadError is the error of a failed SSR load, as returned by SSRLoadError.
+// It unwraps to the error of the failure that ended the load.
+type LoadError struct {
+	Kind     FailureKind
+	Module   string        // offending module when Kind is FailureModule
+	Attempts []LoadAttempt // every ExtractAll call of the load
+	Err      error
+}
+
+func (e *LoadError) Error() string {
+	return fmt.Sprintf("SSR load failed (%s) after %d attempt(s): %v", e.Kind, len(e.Attempts), e.Err)
+}
+
+func (e *LoadError) Unwrap() error { return e.Err }
+
+// goBuildHeader matches the "# import/path" line the go command prints above
+// the compile errors of a package, as in the output of a failed `go run`.
+var goBuildHeader = regexp.MustCompile(`(?m)^# ([^\s]+)\s*$`)
+
+// asModuleError returns err as a *ModuleError when it names, the way the go
+// command does, a package that did not compile: of a known module (see
+// moduleOfPackage) or, before that module is loaded (a failing first load),
+// of the project or a dependency (see projectPackage). A standard package,
+// eg: runtime/cgo, or the generated main (command-line-arguments) leaves err
+// transient. Other errors are returned as is.
+func (c *AssetMin) asModuleError(err error) error {
+	var me *ModuleError
+	if err == nil || errors.As(err, &me) {
+		return err
+	}
+	m := goBuildHeader.FindStringSubmatch(err.Error())
+	if m == nil {
+		return err
+	}
+	c.mu.Lock()
+	name := c.moduleOfPackage(m[1])
+	c.mu.Unlock()
+	if name == "" {
+		name = c.projectPackage(m[1])
+	}
+	if name == "" {
+		return err
+	}
+	return &ModuleError{Module: name, Err: err}
+}
+
+// moduleOfPackage returns the registered module the package pkg belongs to:
+// the one whose name, or the import path of its directory under the go.mod of
+// RootDir, is pkg or prefixes it, the longest winning. "" when none does.
+// It assumes the caller holds c.mu.
+func (c *AssetMin) moduleOfPackage(pkg string) string {
+	best, bestLen := "", 0
+	for name, m := range c.modules {
+		paths := []string{name}
+		if m.Dir != "" {
+			if path := c.importPathOf(m.Dir); path != "" {
+				paths = append(paths, path)
+			}
+		}
+		for _, path := range paths {
+			if (pkg == path || strings.HasPrefix(pkg, path+"/")) && len(path) > bestLen {
+				best, bestLen = name, len(path)
+			}
+		}
+	}
+	return best
+}
+
+// projectPackage returns pkg when it is a package of the project, under the
+// module path in the go.mod of RootDir, or of a dependency, whose import path
+// starts with a domain. "" for the standard library and the generated main.
+func (c *AssetMin) projectPackage(pkg string) string {
+	if c.RootDir != "" {
+		if modulePath, err := readGoModulePath(c.RootDir); err == nil {
+			modulePath = strings.TrimSpace(modulePath)
+			if pkg == modulePath || strings.HasPrefix(pkg, modulePath+"/") {
+				return pkg
+			}
+		}
+	}
+	first, _, _ := strings.Cut(pkg, "/")
+	if strings.Contains(first, ".") {
+		return pkg
+	}
+	return ""
+}
+
+// classifyFailure tells why an ExtractAll call failed.
+func classifyFailure(ctx context.Context, err error) (FailureKind, string) {
+	if ctx.Err() != nil || errors.Is(err, context.Canceled) {
+		return FailureCancelled, ""
+	}
+	var me *ModuleError
+	if errors.As(err, &me) {
+		return FailureModule, me.Module
+	}
+	return FailureTransient, ""
+}
+
+// recordAttempt appends to the history of the current load.
+func (c *AssetMin) recordAttempt(a LoadAttempt) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ssrAttempts = append(c.ssrAttempts, a)
+}
+
+// SSRLoadAttempts returns the ExtractAll calls of the last (or current) SSR
+// load, oldest first.
+func (c *AssetMin) SSRLoadAttempts() []LoadAttempt {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]LoadAttempt(nil), c.ssrAttempts...)
+}
@@ -0,0 +1,144 @@
+package assetmin
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// OutputSink receives every file AssetMin emits: bundles, standalone scripts,
+// source maps, reports, the manifest and the copied fonts. Names are
+// slash-separated paths relative to OutputDir, eg: "style.css".
+//
+// ReadFile and Stat report a missing file with an error matching
+// fs.ErrNotExist; Remove may too.
+type OutputSink interface {
+	WriteFile(name string, content []byte) error
+	ReadFile(name string) ([]byte, error)
+	Stat(name string) (fs.FileInfo, error)
+	Remove(name string) error
+}
+
+// NewDirSink returns the default sink: files under dir, each replaced
+// atomically (see FileWrite).
+func NewDirSink(dir string) OutputSink {
+	return dirSink{dir: dir}
+}
+
+type dirSink struct {
+	dir string
+}
+
+func (s dirSink) path(name string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(name))
+}
+
+func (s dirSink) WriteFile(name string, content []byte) error {
+	return FileWrite(s.path(name), *bytes.NewBuffer(content))
+}
+
+func (s dirSink) ReadFile(name string) ([]byte, error)  { return os.ReadFile(s.path(name)) }
+func (s dirSink) Stat(name string) (fs.FileInfo, error) { return os.Stat(s.path(name)) }
+func (s dirSink) Remove(name string) error              { return os.Remove(s.path(name)) }
+
+// MemorySink keeps the outputs in memory, eg: for tests or to hand a build to
+// an uploader. It is safe for concurrent use.
+type MemorySink struct {
+	mu    sync.RWMutex
+	files map[string]memoryFile
+}
+
+type memoryFile struct {
+	content []byte
+	modTime time.Time
+}
+
+// NewMemorySink returns an empty in-memory sink.
+func NewMemorySink() *MemorySink {
+	return &MemorySink{files: make(map[string]memoryFile)}
+}
+
+func (s *MemorySink) WriteFile(name string, content []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files[name] = memoryFile{content: bytes.Clone(content), modTime: time.Now()}
+	return nil
+}
+
+func (s *MemorySink) ReadFile(name string) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	f, ok := s.files[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrNotExist}
+	}
+	return bytes.Clone(f.content), nil
+}
+
+func (s *MemorySink) Stat(name string) (fs.FileInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	f, ok := s.files[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	return fileInfo{name: path.Base(name), size: int64(len(f.content)), modTime: f.modTime}, nil
+}
+
+func (s *MemorySink) Remove(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.files[name]; !ok {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+	delete(s.files, name)
+	return nil
+}
+
+// Names returns the stored file names, sorted.
+func (s *MemorySink) Names() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	names := make([]string, 0, len(s.files))
+	for name := range s.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// fileInfo is the fs.FileInfo of a file (or directory) held in memory.
+type fileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	dir     bool
+}
+
+func (fi fileInfo) Name() string       { return fi.name }
+func (fi fileInfo) Size() int64        { return fi.size }
+func (fi fileInfo) ModTime() time.Time { return fi.modTime }
+func (fi fileInfo) IsDir() bool        { return fi.dir }
+func (fi fileInfo) Sys() any           { return nil }
+
+func (fi fileInfo) Mode() fs.FileMode {
+	if fi.dir {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+// sinkName converts an output path (OutputDir joined with a name) into the
+// sink name.
+func (c *AssetMin) sinkName(outputPath string) string {
+	rel, err := filepath.Rel(c.OutputDir, outputPath)
+	if err != nil {
+		rel = filepath.Base(outputPath)
+	}
+	return filepath.ToSlash(rel)
+}
@@ -0,0 +1,244 @@
+package assetmin
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/tdewolff/minify/v2"
+	"github.com/tinywasm/font"
+)
+
+// sizeReportFileName is the JSON side output of FlushToDisk when Config.WriteSizeReport is set.
+const sizeReportFileName = "asset-sizes.json"
+
+// SizeReport lists what every module contributes to the emitted assets.
+// Minified sizes are production sizes: they always use the minifier, whatever
+// the minify toggle, and include post-processing such as CSS pruning.
+type SizeReport struct {
+	Assets []AssetSize `json:"assets"`
+	Icons  []IconSize  `json:"icons"`
+	Fonts  []FileSize  `json:"fonts"`
+}
+
+// AssetSize is the size of one output asset and of each of its contributors.
+type AssetSize struct {
+	Name     string        `json:"name"`     // eg: style.css
+	Raw      int           `json:"raw"`      // bytes before minification
+	Minified int           `json:"minified"` // bytes after minification
+	Contents []ContentSize `json:"contents"` // contributors in bundle order
+}
+
+// ContentSize is the size one ContentFile contributes to an asset.
+type ContentSize struct {
+	Slot     string `json:"slot"` // open, middle or close
+	Path     string `json:"path"` // module name or source file
+	Raw      int    `json:"raw"`
+	Minified int    `json:"minified"`
+}
+
+// IconSize is the size of one sprite icon body and the module declaring it.
+type IconSize struct {
+	ID     string `json:"id"`
+	Module string `json:"module"`
+	Size   int    `json:"size"`
+}
+
+// FileSize is the size of a file copied verbatim to the output (fonts).
+type FileSize struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+}
+
+// SizeBudget caps the minified size of an asset, of a module inside an asset,
+// or of a module across every asset:
+//   - Asset set, Module empty: the whole asset.
+//   - Asset and Module set: that module's contribution to that asset.
+//   - Asset empty, Module set: that module's contributions summed over all assets.
+type SizeBudget struct {
+	Asset    string // output name, eg: style.css
+	Module   string // ContentFile.Path, eg: github.com/acme/ui
+	MaxBytes int
+}
+
+// BudgetViolation is a budget exceeded by the current outputs.
+type BudgetViolation struct {
+	Budget SizeBudget
+	Actual int
+}
+
+func (v BudgetViolation) String() string {
+	target := v.Budget.Asset
+	switch {
+	case v.Budget.Asset == "":
+		target = v.Budget.Module + " (all assets)"
+	case v.Budget.Module != "":
+		target = v.Budget.Module + " in " + v.Budget.Asset
+	}
+	return fmt.Sprintf("%s is %d bytes, budget %d", target, v.Actual, v.Budget.MaxBytes)
+}
+
+// BudgetError is returned by FlushToDisk when outputs exceed Config.SizeBudgets.
+type BudgetError struct {
+	Violations []BudgetViolation
+}
+
+func (e *BudgetError) Error() string {
+	parts := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		parts[i] = v.String()
+	}
+	return "size budget exceeded: " + strings.Join(parts, "; ")
+}
+
+// SizeReport measures every output asset, sprite icon and font.
+func (c *AssetMin) SizeReport() (*SizeReport, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.sizeReport()
+}
+
+// sizeReport assumes the caller holds c.mu.
+func (c *AssetMin) sizeReport() (*SizeReport, error) {
+	report := &SizeReport{}
+
+	names := make([]string, 0, len(c.allAssets))
+	byName := make(map[string]*asset, len(c.allAssets))
+	for _, a := range c.emittedAssets() {
+		names = append(names, a.fileOutputName)
+		byName[a.fileOutputName] = a
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		size, err := byName[name].measure(c.min)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+		report.Assets = append(report.Assets, size)
+	}
+
+	c.spriteMu.RLock()
+	modules := make([]string, 0, len(c.moduleSprites))
+	for name := range c.moduleSprites {
+		modules = append(modules, name)
+	}
+	sort.Strings(modules)
+	for _, name := range modules {
+		for _, def := range c.moduleSprites[name].Icons() {
+			report.Icons = append(report.Icons, IconSize{ID: def.Icon.ID(), Module: name, Size: len(def.Body)})
+		}
+	}
+	c.spriteMu.RUnlock()
+
+	c.fontsMu.RLock()
+	d := c.fonts
+	c.fontsMu.RUnlock()
+	if d.Family() != "" {
+		for s := font.Regular; s <= font.BoldItalic; s++ {
+			name := d.Family().Face(s) + ".ttf"
+			info, err := os.Stat(filepath.Join(c.RootDir, d.Dir(), name))
+			if os.IsNotExist(err) {
+				continue // an absent face is left out, not fatal to the report
+			}
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			report.Fonts = append(report.Fonts, FileSize{Name: name, Size: info.Size()})
+		}
+	}
+
+	return report, errors.Join(errs...)
+}
+
+// measure returns the raw and minified size of the asset and of each of its
+// ContentFiles, post-processed as the served output is.
+func (h *asset) measure(minifier *minify.M) (AssetSize, error) {
+	step := h.transformStep()
+	process := func(content []byte) (int, error) {
+		if step != nil {
+			content = step(content)
+		}
+		minified, err := minifier.Bytes(h.mediatype, content)
+		return len(minified), err
+	}
+
+	h.mu.RLock()
+	var buf bytes.Buffer
+	h.WriteContent(&buf)
+	segments := h.segments()
+	h.mu.RUnlock()
+
+	size := AssetSize{Name: h.fileOutputName, Raw: buf.Len()}
+	var errs []error
+	var err error
+	if size.Minified, err = process(buf.Bytes()); err != nil {
+		errs = append(errs, err)
+	}
+	for _, s := range segments {
+		if s.source == "" {
+			continue
+		}
+		cs := ContentSize{Slot: s.slot, Path: s.source, Raw: len(s.content)}
+		if cs.Minified, err = process(s.content); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.source, err))
+		}
+		size.Contents = append(size.Contents, cs)
+	}
+	return size, errors.Join(errs...)
+}
+
+// CheckBudgets returns every budget in budgets exceeded by the report.
+func (r *SizeReport) CheckBudgets(budgets []SizeBudget) []BudgetViolation {
+	var out []BudgetViolation
+	for _, b := range budgets {
+		actual := 0
+		for _, a := range r.Assets {
+			if b.Asset != "" && a.Name != b.Asset {
+				continue
+			}
+			if b.Module == "" {
+				actual += a.Minified
+				continue
+			}
+			for _, cs := range a.Contents {
+				if cs.Path == b.Module {
+					actual += cs.Minified
+				}
+			}
+		}
+		if actual > b.MaxBytes {
+			out = append(out, BudgetViolation{Budget: b, Actual: actual})
+		}
+	}
+	return out
+}
+
+// enforceBudgets checks Config.SizeBudgets against report. In DevMode an
+// exceeded budget is only logged; otherwise it is returned as a *BudgetError.
+func (c *AssetMin) enforceBudgets(report *SizeReport) error {
+	violations := report.CheckBudgets(c.SizeBudgets)
+	if len(violations) == 0 {
+		return nil
+	}
+	err := &BudgetError{Violations: violations}
+	if c.DevMode {
+		for _, v := range violations {
+			c.writeMessage("WARNING: SIZE BUDGET EXCEEDED:", v.String())
+		}
+		return nil
+	}
+	return err
+}
+
+// sizeReportJSON renders the report written next to the assets.
+func sizeReportJSON(report *SizeReport) ([]byte, error) {
+	return json.MarshalIndent(report, "", "  ")
+}
@@ -0,0 +1,236 @@
+package assetmin
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/tdewolff/minify/v2"
+	"github.com/tinywasm/router"
+)
+
+// sourceMapV3 is the JSON document of a revision 3 source map.
+type sourceMapV3 struct {
+	Version        int      `json:"version"`
+	File           string   `json:"file"`
+	Sources        []string `json:"sources"`
+	SourcesContent []string `json:"sourcesContent"`
+	Names          []string `json:"names"`
+	Mappings       string   `json:"mappings"`
+}
+
+// mapping ties a generated position to a position in one source.
+type mapping struct {
+	genLine, genCol   int
+	source            int
+	origLine, origCol int
+}
+
+// anchorSlack is how far from the position the lengths predict
+// buildSourceMap looks for the start of a contributor in the bundle.
+const anchorSlack = 16
+
+// buildSourceMap maps out, the bundle build made of written, back to the
+// contributors of the asset. When pruning or minification rewrote the bundle,
+// each contributor is processed once on its own: it starts where the lengths
+// of those before it say, moved to where its processed head is found nearby,
+// so the bundle itself is the same with maps on or off. The mappings are
+// line-exact only when nothing rewrote the bundle: otherwise they are
+// module-granular, every generated line of a contributor pointing at the start
+// of its source. It assumes the caller holds h.mu.
+func (h *asset) buildSourceMap(written, out []byte, minifier *minify.M, step func([]byte) []byte) ([]byte, error) {
+	segs := h.segments()
+	exact := bytes.Equal(out, written)
+
+	// starts[k] is where segment k begins in out; starts[len(segs)] its end.
+	starts := make([]int, len(segs)+1)
+	next := 0 // where the lengths put the next segment
+	for k, s := range segs {
+		if exact {
+			starts[k] = next
+			next += len(s.content)
+			if s.newline {
+				next++
+			}
+			continue
+		}
+		lo := 0
+		if k > 0 {
+			lo = starts[k-1]
+		}
+		start := min(max(next, lo), len(out))
+		// A segment that does not build on its own keeps the predicted start.
+		built, err := h.process(s.content, minifier, step)
+		if err != nil {
+			built = nil
+		}
+		if head := built[:min(len(built), anchorSlack)]; len(head) > 0 {
+			from, to := max(lo, start-anchorSlack), min(len(out), start+anchorSlack+len(head))
+			if i := bytes.Index(out[from:to], head); i >= 0 {
+				start = from + i
+			}
+		}
+		starts[k] = start
+		next = start + len(built)
+	}
+	starts[len(segs)] = len(out)
+
+	doc := sourceMapV3{Version: 3, File: h.fileOutputName, Sources: []string{}, SourcesContent: []string{}, Names: []string{}}
+	sourceIndex := make(map[string]int)
+	var maps []mapping
+	pos, line, col := 0, 0, 0
+	for k, s := range segs {
+		start, end := starts[k], starts[k+1]
+		if s.source == "" || start >= end {
+			continue
+		}
+		line, col = advancePosition(line, col, out[pos:start])
+		pos = start
+		idx, ok := sourceIndex[s.source]
+		if !ok {
+			idx = len(doc.Sources)
+			sourceIndex[s.source] = idx
+			doc.Sources = append(doc.Sources, s.source)
+			doc.SourcesContent = append(doc.SourcesContent, string(s.content))
+		}
+		maps = append(maps, mapping{genLine: line, genCol: col, source: idx})
+		// Every further line of the contributor, up to its last character.
+		body := out[start:end]
+		for n, i := 1, 0; ; n++ {
+			j := bytes.IndexByte(body[i:], '\n')
+			if j < 0 || i+j+1 == len(body) {
+				break
+			}
+			i += j + 1
+			m := mapping{genLine: line + n, source: idx}
+			if exact {
+				m.origLine = n
+			}
+			maps = append(maps, m)
+		}
+	}
+
+	doc.Mappings = encodeMappings(maps)
+	return json.Marshal(doc)
+}
+
+// advancePosition returns the generated line and column after writing content
+// at line, col.
+func advancePosition(line, col int, content []byte) (int, int) {
+	if n := bytes.Count(content, []byte("\n")); n > 0 {
+		return line + n, len(content) - bytes.LastIndexByte(content, '\n') - 1
+	}
+	return line, col + len(content)
+}
+
+// sourceMappingComment returns the trailing comment that links a bundle to its map.
+func sourceMappingComment(mediatype, url string) string {
+	if strings.Contains(mediatype, "css") {
+		return "/*# sourceMappingURL=" + url + " */\n"
+	}
+	return "//# sourceMappingURL=" + url + "\n"
+}
+
+// encodeMappings serializes mappings (ordered by generated position) into the
+// "mappings" field: lines separated by ';', segments by ',', each segment a
+// list of Base64 VLQ deltas.
+func encodeMappings(maps []mapping) string {
+	var b strings.Builder
+	var prevLine, prevCol, prevSource, prevOrigLine, prevOrigCol int
+	first := true
+	for _, m := range maps {
+		for prevLine < m.genLine {
+			b.WriteByte(';')
+			prevLine++
+			prevCol = 0
+			first = true
+		}
+		if !first {
+			b.WriteByte(',')
+		}
+		first = false
+		writeVLQ(&b, m.genCol-prevCol)
+		writeVLQ(&b, m.source-prevSource)
+		writeVLQ(&b, m.origLine-prevOrigLine)
+		writeVLQ(&b, m.origCol-prevOrigCol)
+		prevCol, prevSource, prevOrigLine, prevOrigCol = m.genCol, m.source, m.origLine, m.origCol
+	}
+	return b.String()
+}
+
+const base64VLQ = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+func writeVLQ(b *strings.Builder, v int) {
+	u := v << 1
+	if v < 0 {
+		u = (-v << 1) | 1
+	}
+	for {
+		digit := u & 31
+		u >>= 5
+		if u > 0 {
+			digit |= 32
+		}
+		b.WriteByte(base64VLQ[digit])
+		if u == 0 {
+			return
+		}
+	}
+}
+
+// GetSourceMap returns the cached source map of the asset, or nil when the
+// asset has no source map.
+func (h *asset) GetSourceMap() []byte {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.cachedMap
+}
+
+// enableSourceMaps turns on source maps for style.css and script.js. The maps
+// are referenced from the bundles only where they can be fetched: served in
+// DevMode, or written next to the bundles by FlushToDisk.
+func (c *AssetMin) enableSourceMaps() {
+	for _, h := range []*asset{c.mainStyleCssHandler, c.mainJsHandler} {
+		h.sourceMap = true
+		if c.DevMode || c.WriteSourceMaps {
+			h.sourceMapURL = h.fileOutputName + ".map"
+		}
+	}
+}
+
+// sourceMapOutputs returns the assets whose source map goes to disk next to them.
+func (c *AssetMin) sourceMapOutputs() []*asset {
+	if !c.SourceMaps || !c.WriteSourceMaps {
+		return nil
+	}
+	return []*asset{c.mainStyleCssHandler, c.mainJsHandler}
+}
+
+// GetCSSSourceMap returns the source map of style.css, or nil when disabled.
+func (c *AssetMin) GetCSSSourceMap() ([]byte, error) {
+	if _, err := c.mainStyleCssHandler.GetMinifiedContent(c.min); err != nil {
+		return nil, err
+	}
+	return c.mainStyleCssHandler.GetSourceMap(), nil
+}
+
+// GetJSSourceMap returns the source map of script.js, or nil when disabled.
+func (c *AssetMin) GetJSSourceMap() ([]byte, error) {
+	if _, err := c.mainJsHandler.GetMinifiedContent(c.min); err != nil {
+		return nil, err
+	}
+	return c.mainJsHandler.GetSourceMap(), nil
+}
+
+func (c *AssetMin) serveSourceMap(asset *asset) router.HandlerFunc {
+	return func(ctx router.Context) {
+		if _, err := asset.GetMinifiedContent(c.min); err != nil {
+			ctx.WriteStatus(500)
+			ctx.Write([]byte("Error getting minified content"))
+			return
+		}
+		ctx.SetHeader("Content-Type", "application/json")
+		ctx.SetHeader("Cache-Control", "no-cache, no-store, must-revalidate")
+		ctx.Write(asset.GetSourceMap())
+	}
+}
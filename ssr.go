@@ -1,8 +1,11 @@
 package assetmin
 
 import (
-	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"path/filepath"
 	"sort"
 )
 
@@ -21,40 +24,157 @@ func (c *AssetMin) SetSSRCompiler(fn func() error) {
 	c.onSSRCompile = fn
 }
 
-// FlushToDisk snapshots all registered assets, writes them to disk (overwrite),
-// and sets diskMirrored = true only on full success. Returns the first write error.
+// FlushToDisk writes every output to disk; see Flush. It returns the joined
+// errors of the flush.
 func (c *AssetMin) FlushToDisk() error {
+	_, err := c.Flush()
+	return err
+}
+
+// FlushToDiskContext is FlushToDisk cancelled by ctx; see FlushContext.
+func (c *AssetMin) FlushToDiskContext(ctx context.Context) error {
+	_, err := c.FlushContext(ctx)
+	return err
+}
+
+// Flush snapshots all registered assets, writes those whose content changed
+// to disk and sets diskMirrored = true only on full success. Unchanged files
+// are not rewritten. Outputs exceeding Config.SizeBudgets fail the flush
+// before anything is written (in DevMode they are only logged).
+// asset-manifest.json describing every output is written alongside. Each file
+// is replaced atomically, and files a previous flush (or run, per its
+// manifest) wrote but that are no longer produced are removed.
+//
+// A failed write does not stop the others: every error is returned, joined,
+// with the result of what was done. Orphans are only removed on full success.
+func (c *AssetMin) Flush() (*FlushResult, error) {
+	return c.FlushContext(context.Background())
+}
+
+// FlushContext is Flush cancelled by ctx or Close: no file is written once
+// ctx is done, and the error includes ctx.Err(). Orphans are kept and the
+// flush does not count as complete. After Close it returns ErrClosed.
+func (c *AssetMin) FlushContext(ctx context.Context) (*FlushResult, error) {
 	type snapshot struct {
 		path    string
 		content []byte
 	}
+	result := &FlushResult{}
+
+	done, err := c.track()
+	if err != nil {
+		return result, err
+	}
+	defer done()
+	ctx, cancel := c.lifetime(ctx)
+	defer cancel()
 
 	c.mu.Lock()
+	c.ownedOutputs() // seed from the previous manifest before it is overwritten
 	// Combine regular assets and standalone assets
 	totalAssets := len(c.allAssets)
 	snapshots := make([]snapshot, 0, totalAssets)
 
 	for _, a := range c.allAssets {
 		a.RegenerateCache(c.activeMinifier())
+		if !c.emitted(a) {
+			continue
+		}
 		snapshots = append(snapshots, snapshot{
 			path:    a.outputPath,
 			content: a.GetCachedMinified(),
 		})
 	}
+	for _, a := range c.sourceMapOutputs() {
+		snapshots = append(snapshots, snapshot{
+			path:    a.outputPath + ".map",
+			content: a.GetSourceMap(),
+		})
+	}
+
+	if len(c.SizeBudgets) > 0 || c.WriteSizeReport {
+		report, err := c.sizeReport()
+		if err != nil {
+			c.mu.Unlock()
+			return result, fmt.Errorf("FlushToDisk size report: %w", err)
+		}
+		if err := c.enforceBudgets(report); err != nil {
+			c.mu.Unlock()
+			return result, fmt.Errorf("FlushToDisk: %w", err)
+		}
+		if c.WriteSizeReport {
+			content, err := sizeReportJSON(report)
+			if err != nil {
+				c.mu.Unlock()
+				return result, fmt.Errorf("FlushToDisk size report: %w", err)
+			}
+			snapshots = append(snapshots, snapshot{
+				path:    filepath.Join(c.OutputDir, sizeReportFileName),
+				content: content,
+			})
+		}
+	}
+
+	manifest, err := c.manifest()
+	if err != nil {
+		c.mu.Unlock()
+		return result, fmt.Errorf("FlushToDisk manifest: %w", err)
+	}
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		c.mu.Unlock()
+		return result, fmt.Errorf("FlushToDisk manifest: %w", err)
+	}
+	snapshots = append(snapshots, snapshot{
+		path:    filepath.Join(c.OutputDir, manifestFileName),
+		content: manifestJSON,
+	})
 	c.mu.Unlock()
 
 	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].path < snapshots[j].path })
 
+	var errs []error
 	for _, s := range snapshots {
-		if err := FileWrite(s.path, *bytes.NewBuffer(s.content)); err != nil {
-			return fmt.Errorf("FlushToDisk %s: %w", s.path, err)
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, fmt.Errorf("FlushToDisk: %w", err))
+			break
+		}
+		written, err := c.writeOutput(s.path, s.content)
+		switch {
+		case err != nil:
+			errs = append(errs, fmt.Errorf("FlushToDisk %s: %w", s.path, err))
+		case written:
+			result.Written = append(result.Written, s.path)
+		default:
+			result.Unchanged = append(result.Unchanged, s.path)
 		}
 	}
+	if len(errs) > 0 {
+		return result, errors.Join(errs...)
+	}
+
+	// Fonts are copied by their own pipeline, images written by the
+	// ImageProcessor: only the fonts are AssetMin's.
+	current := make(map[string]bool, len(snapshots)+4)
+	for _, s := range snapshots {
+		current[s.path] = true
+	}
+	for _, path := range c.fontOutputPaths() {
+		current[path] = true
+	}
 
 	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return result, ErrClosed
+	}
+	removed, err := c.removeOrphans(current)
+	result.Removed = removed
+	if err != nil {
+		return result, fmt.Errorf("FlushToDisk removing orphans: %w", err)
+	}
 	c.diskMirrored = true
-	c.mu.Unlock()
-	return nil
+	return result, nil
 }
 
 // isSSRMode returns true if the package is being used as a dependency (SSR mode).
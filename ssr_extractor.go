@@ -17,6 +17,7 @@ type SSRAssets struct {
 	Fonts       font.Declaration // familia declarada por el módulo; cero-valor = ninguna
 	IsRoot      bool
 	IsFramework bool
+	Dir         string // directorio del módulo, si el extractor lo conoce (ver Modules)
 }
 
 // SSRExtractor lo implementa github.com/tinywasm/ssr; lo inyecta app.
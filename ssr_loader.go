@@ -1,10 +1,13 @@
 package assetmin
 
 import (
+	"context"
 	"path/filepath"
+	"slices"
 	"time"
 
 	"github.com/tinywasm/fmt"
+	"github.com/tinywasm/js"
 )
 
 // cssModulePath is the module path that provides the default `:root` theme
@@ -15,6 +18,11 @@ func (c *AssetMin) LoadSSRModules() {
 	c.ScheduleSSRLoad()
 }
 
+// LoadSSRModulesContext is LoadSSRModules cancelled by ctx; see ScheduleSSRLoadContext.
+func (c *AssetMin) LoadSSRModulesContext(ctx context.Context) {
+	c.ScheduleSSRLoadContext(ctx)
+}
+
 // ScheduleSSRLoad inicia la carga de módulos SSR en segundo plano de forma segura.
 //
 // El escaneo de archivos (ExtractAll / LoadImages) es IO lento y se ejecuta SIN
@@ -23,47 +31,85 @@ func (c *AssetMin) LoadSSRModules() {
 // que un cambio entrante quedara parado hasta que terminara todo el escaneo.
 // El lock se toma solo al final, para aplicar las mutaciones de estado compartido.
 func (c *AssetMin) ScheduleSSRLoad() {
-	c.ssrLoading.Add(1)
+	c.ScheduleSSRLoadContext(context.Background())
+}
+
+// ScheduleSSRLoadContext is ScheduleSSRLoad cancelled by ctx or Close: the
+// extraction (see SSRContextExtractor) and the waits between its retries stop,
+// and nothing extracted is applied. A cancelled load fails with ctx.Err().
+// After Close it does nothing.
+func (c *AssetMin) ScheduleSSRLoadContext(ctx context.Context) {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		c.Logger("ScheduleSSRLoad:", ErrClosed)
+		return
+	}
+	c.ssrLoading.Add(1) // under c.mu so Close never waits before a load it let start
+	c.startSSRLoad()
+	c.mu.Unlock()
+
+	ctx, cancel := c.lifetime(ctx)
 	go func() {
 		defer c.ssrLoading.Done()
+		defer c.finishSSRLoad()
+		defer cancel()
 
 		// Snapshot de las dependencias inyectadas bajo un lock breve.
 		c.mu.Lock()
 		ssrExtractor := c.ssrExtractor
 		imageProcessor := c.imageProcessor
+		policy := c.SSRRetry.withDefaults()
+		c.ssrAttempts = nil
 		c.mu.Unlock()
 
 		// 1) assets de texto/svg vía el extractor SSR inyectado (IO, sin lock):
 		var extracted []*SSRAssets
 		var extractSuccess bool
 		if ssrExtractor != nil {
-			var err error
-			attempts := 5
-			backoff := 200 * time.Millisecond
-			for i := 1; i <= attempts; i++ {
-				extracted, err = ssrExtractor.ExtractAll()
+			var failure *LoadAttempt
+			for i := 1; i <= policy.Attempts; i++ {
+				start := time.Now()
+				var err error
+				extracted, err = extractAll(ctx, ssrExtractor)
+				err = c.asModuleError(err)
+				attempt := LoadAttempt{Start: start, Duration: time.Since(start), Err: err}
+				if err != nil {
+					attempt.Kind, attempt.Module = classifyFailure(ctx, err)
+				}
+				c.recordAttempt(attempt)
 				if err == nil {
 					extractSuccess = true
 					break
 				}
-				c.Logger("SSR ExtractAll attempt failed:", err)
-				if i < attempts {
-					time.Sleep(backoff)
-					backoff *= 2
-					if backoff > 3*time.Second {
-						backoff = 3 * time.Second
+				failure = &attempt
+				if attempt.Kind != FailureTransient {
+					break // retrying cannot fix a broken module or a cancelled load
+				}
+				c.Logger("SSR ExtractAll attempt", i, "of", policy.Attempts, "failed:", err)
+				if i < policy.Attempts {
+					if err := sleepContext(ctx, policy.backoff(i)); err != nil {
+						failure = &LoadAttempt{Err: err, Kind: FailureCancelled}
+						break
 					}
 				}
 			}
-			if err != nil {
-				c.Logger("FATAL: SSR ExtractAll failed permanently after", attempts, "attempts. Error:", err)
+			if !extractSuccess {
 				c.mu.Lock()
+				loadErr := &LoadError{Kind: failure.Kind, Module: failure.Module, Attempts: slices.Clone(c.ssrAttempts), Err: failure.Err}
+				if loadErr.Kind == FailureModule {
+					c.recordModuleError(loadErr.Module, "", loadErr.Err)
+				}
+				if loadErr.Kind != FailureCancelled {
+					c.Logger("FATAL:", loadErr)
+				}
 				c.initialLoadFailed = true
+				c.ssrLoadErr = loadErr
 				c.mu.Unlock()
 			}
 		}
 		// 2) imágenes vía el ImageProcessor inyectado (IO, sin lock):
-		if imageProcessor != nil {
+		if imageProcessor != nil && ctx.Err() == nil {
 			if err := imageProcessor.LoadImages(); err != nil {
 				c.Logger("image load error:", err)
 			}
@@ -71,18 +117,44 @@ func (c *AssetMin) ScheduleSSRLoad() {
 
 		// Aplicar mutaciones de estado compartido bajo el lock.
 		c.mu.Lock()
+		if err := ctx.Err(); err != nil || c.closed {
+			// Cancelled: apply and write nothing.
+			if err == nil {
+				err = ErrClosed
+			}
+			c.initialLoadFailed = true // the next module event retries the full scan
+			c.ssrLoadErr = &LoadError{Kind: FailureCancelled, Attempts: slices.Clone(c.ssrAttempts), Err: err}
+			c.mu.Unlock()
+			return
+		}
+		frozen := c.freezeOutputs()
 		for _, a := range extracted {
-			if err := c.routeAssets(a, a.IsRoot, a.IsFramework); err != nil {
-				c.Logger("FATAL:", err)
+			if err := c.routeAssets(a, "", a.IsRoot, a.IsFramework); err != nil {
+				loadErr := &LoadError{Kind: FailureModule, Module: a.ModuleName, Attempts: slices.Clone(c.ssrAttempts), Err: &ModuleError{Module: a.ModuleName, Err: err}}
+				c.Logger("FATAL:", loadErr)
 				c.initialLoadFailed = true
+				c.ssrLoadErr = loadErr
 				extractSuccess = false
 				break
 			}
 		}
+		if extractSuccess {
+			c.ssrLoadErr = nil
+			if ssrExtractor != nil {
+				c.dropVanishedModules(extracted)
+			}
+		}
 		c.resolveAndApplyRootCSS()
+		// Warm-started outputs are replaced only by a complete load; after a
+		// failure they stay until a retry succeeds.
+		released := false
+		if ssrExtractor == nil || extractSuccess {
+			released = c.releaseWarmStart()
+		}
+		thawOutputs(frozen)
 		c.mu.Unlock()
 
-		if ssrExtractor != nil && extractSuccess {
+		if (ssrExtractor != nil && extractSuccess) || released {
 			c.refreshAsset(".svg")
 			c.refreshAsset(".css")
 			c.refreshAsset(".js")
@@ -91,7 +163,9 @@ func (c *AssetMin) ScheduleSSRLoad() {
 	}()
 }
 
-func (c *AssetMin) routeAssets(a *SSRAssets, isRoot, isFramework bool) error {
+// routeAssets applies one extracted module and records it in the module
+// registry; dir is its directory when known. It assumes the caller holds c.mu.
+func (c *AssetMin) routeAssets(a *SSRAssets, dir string, isRoot, isFramework bool) error {
 	if isRoot {
 		c.fromRoot = nil
 	} else if isFramework {
@@ -112,6 +186,7 @@ func (c *AssetMin) routeAssets(a *SSRAssets, isRoot, isFramework bool) error {
 	if a.Fonts.Family() != "" {
 		if isRoot {
 			if err := c.copyDeclaredFonts(a.Fonts); err != nil {
+				c.recordModuleError(a.ModuleName, dir, err)
 				return err
 			}
 			c.setFonts(a.Fonts)
@@ -132,10 +207,41 @@ func (c *AssetMin) routeAssets(a *SSRAssets, isRoot, isFramework bool) error {
 		slot = "close"
 	}
 	// RootCSS deliberately NOT passed here — it has its own slot resolution above.
-	c.updateSSRModuleInSlot(a.ModuleName, a.CSS, a.JS, a.HTML, a.Icons, slot)
+	_, known := c.modules[a.ModuleName]
+	prev := c.moduleKinds(a.ModuleName)
+	c.recordModule(a, dir, isRoot, isFramework)
+	if err := c.updateSSRModuleInSlot(a.ModuleName, a.CSS, a.JS, a.HTML, a.Icons, slot); err != nil {
+		// Not fatal to the load (the rest of the module is applied), but visible in Modules().
+		c.recordModuleError(a.ModuleName, dir, err)
+	}
+	c.retractEmptyKinds(a, prev, known)
 	return nil
 }
 
+// retractEmptyKinds clears the CSS, bundled JS and HTML the module no longer
+// provides, so deleting its css.go has the same effect as emptying it
+// (updateSSRModuleInSlot only writes what is present). Only the kinds in prev,
+// those of its last extraction, are cleared, so a reload leaves the caches of
+// the other outputs valid; a module not known from an extraction (known false)
+// may hold any kind. Sprite icons and standalone scripts are already replaced
+// as a whole. The root theme lives in the "open" slot and is resolved
+// separately.
+func (c *AssetMin) retractEmptyKinds(a *SSRAssets, prev []string, known bool) {
+	had := func(kind string) bool { return !known || slices.Contains(prev, kind) }
+	bundled := slices.ContainsFunc(a.JS, func(s *js.Script) bool { return s.Name == "" && s.Content != "" })
+	for _, slot := range [2]string{"middle", "close"} {
+		if a.CSS == "" && had("css") {
+			c.mainStyleCssHandler.UpdateContentInSlot(a.ModuleName, "remove", nil, slot)
+		}
+		if !bundled && had("js") {
+			c.mainJsHandler.UpdateContentInSlot(a.ModuleName, "remove", nil, slot)
+		}
+		if a.HTML == "" && had("html") {
+			c.indexHtmlHandler.UpdateContentInSlot(a.ModuleName, "remove", nil, slot)
+		}
+	}
+}
+
 func (c *AssetMin) resolveAndApplyRootCSS() {
 	var entries []*ContentFile
 	if c.fromRoot != nil {
@@ -151,7 +257,27 @@ func (c *AssetMin) resolveAndApplyRootCSS() {
 }
 
 func (c *AssetMin) ReloadSSRModule(moduleDir string) error {
+	return c.ReloadSSRModuleContext(context.Background(), moduleDir)
+}
+
+// ReloadSSRModuleContext is ReloadSSRModule cancelled by ctx or Close: it
+// returns ctx.Err() without applying the extracted module. After Close it
+// returns ErrClosed.
+func (c *AssetMin) ReloadSSRModuleContext(ctx context.Context, moduleDir string) error {
+	refresh, err := c.reloadSSRModule(ctx, moduleDir)
+	c.refreshExtensions(refresh)
+	return err
+}
+
+// reloadSSRModule applies the module extracted from moduleDir and returns the
+// extensions of the outputs to refresh; the caller refreshes them, once per
+// batch of reloads.
+func (c *AssetMin) reloadSSRModule(ctx context.Context, moduleDir string) (map[string]bool, error) {
 	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, ErrClosed
+	}
 	retryFullScan := c.initialLoadFailed
 	if retryFullScan {
 		c.initialLoadFailed = false
@@ -162,22 +288,40 @@ func (c *AssetMin) ReloadSSRModule(moduleDir string) error {
 	}
 
 	if c.ssrExtractor == nil {
-		return nil
+		return nil, nil
 	}
 
-	a, err := c.ssrExtractor.ExtractModule(moduleDir)
-	if err != nil || a == nil {
-		return err
+	ctx, cancel := c.lifetime(ctx)
+	defer cancel()
+	a, err := extractModule(ctx, c.ssrExtractor, moduleDir)
+	err = c.asModuleError(err)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err != nil {
+		c.mu.Lock()
+		c.recordModuleError("", moduleDir, err)
+		c.mu.Unlock()
+		return nil, err
+	}
+	if a == nil {
+		return nil, nil
 	}
 
 	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, ErrClosed
+	}
 	isFramework := a.IsFramework || fmt.Contains(moduleDir, cssModulePath)
 	isRoot := a.IsRoot || isRootDir(moduleDir, c.RootDir)
 
-	err = c.routeAssets(a, isRoot, isFramework)
+	// Kinds the module contributed before: those it dropped are refreshed too.
+	refresh := kindExtensions(c.moduleKinds(a.ModuleName))
+	err = c.routeAssets(a, moduleDir, isRoot, isFramework)
 	if err != nil {
 		c.mu.Unlock()
-		return err
+		return nil, err
 	}
 
 	if isFramework || isRoot || a.RootCSS != "" {
@@ -185,25 +329,33 @@ func (c *AssetMin) ReloadSSRModule(moduleDir string) error {
 	}
 	c.mu.Unlock()
 
-	// Refresh assets only if they were actually changed/extracted
-	if a.CSS != "" || a.Fonts.Family() != "" {
-		c.refreshAsset(".css")
-	}
-	if len(a.JS) > 0 {
-		c.refreshAsset(".js")
-	}
-	if a.HTML != "" {
-		c.refreshAsset(".html")
-	}
-	if a.Icons != nil {
-		c.refreshAsset(".svg")
-	}
+	// Refresh assets only if they were actually changed/extracted, or emptied
+	refresh[".css"] = refresh[".css"] || a.CSS != "" || a.Fonts.Family() != ""
+	refresh[".js"] = refresh[".js"] || len(a.JS) > 0
+	refresh[".html"] = refresh[".html"] || a.HTML != ""
+	refresh[".svg"] = refresh[".svg"] || a.Icons != nil
+	return refresh, nil
+}
 
-	return nil
+// refreshExtensions refreshes the outputs of the given extensions, once each.
+func (c *AssetMin) refreshExtensions(refresh map[string]bool) {
+	for _, ext := range []string{".css", ".js", ".html", ".svg"} {
+		if refresh[ext] {
+			c.refreshAsset(ext)
+		}
+	}
 }
 
 // WaitForSSRLoad espera a que LoadSSRModules termine, hasta el timeout dado.
 func (c *AssetMin) WaitForSSRLoad(timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	c.WaitForSSRLoadContext(ctx)
+}
+
+// WaitForSSRLoadContext is WaitForSSRLoad until ctx is done: it returns
+// ctx.Err() when the load has not finished by then.
+func (c *AssetMin) WaitForSSRLoadContext(ctx context.Context) error {
 	done := make(chan struct{})
 	go func() {
 		c.ssrLoading.Wait()
@@ -212,10 +364,21 @@ func (c *AssetMin) WaitForSSRLoad(timeout time.Duration) {
 
 	select {
 	case <-done:
-	case <-time.After(timeout):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
 }
 
+// SSRLoadError returns the error of the last completed LoadSSRModules, a
+// *LoadError, or nil when it succeeded (or none ran yet). Call it after
+// WaitForSSRLoad.
+func (c *AssetMin) SSRLoadError() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ssrLoadErr
+}
+
 func isRootDir(dir, rootDir string) bool {
 	if rootDir == "" {
 		return false
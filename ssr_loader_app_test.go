@@ -18,7 +18,7 @@ func TestRouteAssets_RootHTMLInsideApp(t *testing.T) {
 		ModuleName: "example.com/mod",
 		CSS:        ".mod{color:blue}",
 		HTML:       `<section>Module</section>`,
-	}, false, false); err != nil {
+	}, "", false, false); err != nil {
 		t.Fatal(err)
 	}
 
@@ -27,7 +27,7 @@ func TestRouteAssets_RootHTMLInsideApp(t *testing.T) {
 		CSS:        ".root{color:red}",
 		HTML:       `<main>Root</main>`,
 		IsRoot:     true,
-	}, true, false); err != nil {
+	}, "", true, false); err != nil {
 		t.Fatal(err)
 	}
 
@@ -83,7 +83,7 @@ func TestRouteAssets_RootToNonRootTransition(t *testing.T) {
 		CSS:        ".root{color:red}",
 		HTML:       `<main>Root</main>`,
 		IsRoot:     true,
-	}, true, false); err != nil {
+	}, "", true, false); err != nil {
 		t.Fatal(err)
 	}
 
@@ -91,7 +91,7 @@ func TestRouteAssets_RootToNonRootTransition(t *testing.T) {
 		ModuleName: "example.com/root",
 		CSS:        ".root{color:green}",
 		HTML:       `<main>Root v2</main>`,
-	}, false, false); err != nil {
+	}, "", false, false); err != nil {
 		t.Fatal(err)
 	}
 
@@ -117,4 +117,40 @@ func TestRouteAssets_RootToNonRootTransition(t *testing.T) {
 	if !strings.Contains(string(css), "color:green") {
 		t.Errorf("updated CSS rule must be present, got %q", string(css))
 	}
-}
\ No newline at end of file
+}
+
+// Re-extraer un módulo que sólo aporta CSS no debe invalidar los caches de
+// script.js ni index.html: no había nada suyo que retirar de ellos.
+func TestRouteAssets_ReloadKeepsOtherCachesValid(t *testing.T) {
+	c := NewAssetMin(&Config{OutputDir: t.TempDir()})
+	mod := &SSRAssets{ModuleName: "example.com/mod", CSS: ".mod{color:blue}"}
+	if err := c.routeAssets(mod, "", false, false); err != nil {
+		t.Fatal(err)
+	}
+	for _, h := range []*asset{c.mainStyleCssHandler, c.mainJsHandler, c.indexHtmlHandler} {
+		if err := h.RegenerateCache(c.activeMinifier()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mod.CSS = ".mod{color:red}"
+	if err := c.routeAssets(mod, "", false, false); err != nil {
+		t.Fatal(err)
+	}
+	if !c.mainJsHandler.isCacheValid() || !c.indexHtmlHandler.isCacheValid() {
+		t.Error("reloading a CSS-only module invalidated script.js or index.html")
+	}
+
+	// Dejar de aportar CSS sí la retira.
+	mod.CSS = ""
+	if err := c.routeAssets(mod, "", false, false); err != nil {
+		t.Fatal(err)
+	}
+	css, err := c.GetMinifiedCSS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(css), ".mod") {
+		t.Errorf("CSS the module no longer provides is still served: %s", css)
+	}
+}
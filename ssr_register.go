@@ -63,6 +63,9 @@ func (c *AssetMin) UpdateSSRModule(name string, css string, scripts []*js.Script
 func (c *AssetMin) UpdateSSRModuleInSlot(name string, css string, scripts []*js.Script, html string, icons *sprite.Sprite, slot string) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
 	return c.updateSSRModuleInSlot(name, css, scripts, html, icons, slot)
 }
 
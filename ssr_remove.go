@@ -0,0 +1,149 @@
+package assetmin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/tinywasm/font"
+)
+
+// RemoveSSRModule retracts everything the named module contributed: its CSS,
+// JS, HTML and standalone scripts in every slot, its sprite icons, its :root
+// theme and (for the root project) its fonts. Removing an unknown module is a
+// no-op. After Close it returns ErrClosed.
+func (c *AssetMin) RemoveSSRModule(name string) error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return ErrClosed
+	}
+	c.removeSSRModule(name)
+	c.mu.Unlock()
+
+	for _, ext := range []string{".svg", ".css", ".js", ".html"} {
+		c.refreshAsset(ext)
+	}
+	return nil
+}
+
+// removeSSRModule drops name from every handler and registry. It assumes the
+// caller holds c.mu.
+func (c *AssetMin) removeSSRModule(name string) {
+	for _, slot := range []string{"open", "middle", "close"} {
+		c.mainStyleCssHandler.UpdateContentInSlot(name, "remove", nil, slot)
+		c.mainJsHandler.UpdateContentInSlot(name, "remove", nil, slot)
+		c.indexHtmlHandler.UpdateContentInSlot(name, "remove", nil, slot)
+		for _, s := range c.standaloneOwners[name] {
+			if h, ok := c.standaloneJS[s]; ok {
+				h.UpdateContentInSlot(name+":"+s, "remove", nil, slot)
+			}
+		}
+	}
+	delete(c.standaloneOwners, name)
+	c.setModuleSprite(name, nil)
+
+	if c.fromRoot != nil && c.fromRoot.name == name {
+		c.fromRoot = nil
+	}
+	if c.fromCss != nil && c.fromCss.name == name {
+		c.fromCss = nil
+	}
+	if m, ok := c.modules[name]; ok && m.IsRoot {
+		c.setFonts(font.Declaration{})
+	}
+	c.resolveAndApplyRootCSS()
+	delete(c.modules, name)
+}
+
+// modulesInDir returns the registered modules loaded from dir, and with below
+// also those loaded from a directory below it. A module whose directory is
+// unknown is matched by the import path dir has under the go.mod of RootDir.
+// It assumes the caller holds c.mu.
+func (c *AssetMin) modulesInDir(dir string, below bool) []string {
+	var names []string
+	for name, m := range c.modules {
+		if m.Dir != "" && (m.Dir == dir || below && strings.HasPrefix(m.Dir, dir+string(filepath.Separator))) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		if path := c.importPathOf(dir); path != "" {
+			for name, m := range c.modules {
+				if m.Dir == "" && (name == path || below && strings.HasPrefix(name, path+"/")) {
+					names = append(names, name)
+				}
+			}
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// hasModulesIn reports whether a registered module was loaded from dir or
+// from a directory below it (see modulesInDir).
+func (c *AssetMin) hasModulesIn(dir string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.modulesInDir(dir, true)) > 0
+}
+
+// importPathOf derives the import path of dir from the go.mod of RootDir;
+// "" when dir is outside RootDir or there is no go.mod.
+func (c *AssetMin) importPathOf(dir string) string {
+	if c.RootDir == "" {
+		return ""
+	}
+	modulePath, err := readGoModulePath(c.RootDir)
+	if err != nil {
+		return ""
+	}
+	modulePath = strings.TrimSpace(modulePath)
+	rel, err := filepath.Rel(c.RootDir, dir)
+	if err != nil || !filepath.IsLocal(rel) && rel != "." {
+		return ""
+	}
+	if rel == "." {
+		return modulePath
+	}
+	return modulePath + "/" + filepath.ToSlash(rel)
+}
+
+// retractSSRDir handles the removal of an asset source in dir, or with below
+// of dir itself: a module with sources left is re-extracted, every other
+// module loaded from dir is removed, and with below those loaded from a
+// directory below it too. It returns the extensions of the outputs to refresh
+// (see reloadSSRModule) and reports whether anything changed.
+func (c *AssetMin) retractSSRDir(ctx context.Context, dir string, below bool) (map[string]bool, bool, error) {
+	if HasSSRSources(dir) {
+		refresh, err := c.reloadSSRModule(ctx, dir)
+		return refresh, true, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil, false, ErrClosed
+	}
+	refresh := make(map[string]bool)
+	names := c.modulesInDir(dir, below)
+	for _, name := range names {
+		for ext := range kindExtensions(c.moduleKinds(name)) {
+			refresh[ext] = true
+		}
+		c.removeSSRModule(name)
+	}
+	return refresh, len(names) > 0, nil
+}
+
+// HasSSRSources reports whether dir holds a text asset source file the SSR
+// extractor reads (css.go, js.go, svg.go, html.go or fonts.go).
+func HasSSRSources(dir string) bool {
+	for _, name := range ssrTextAssetFiles {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
@@ -19,7 +19,10 @@ var ssrTextAssetFiles = []string{
 const imageAssetFile = "image.go"
 
 // SSRFileWatcher implements devwatch.FilesEventHandlers.
-// Watches .go events; routes only recognized asset-source files.
+// Watches .go, .mod, .sum and .work events: .go events route only recognized
+// asset-source files, and changes to go.mod, go.sum, go.work or go.work.sum
+// (goModuleFiles) trigger a debounced full rescan that loads new modules and
+// removes the vanished ones.
 //
 // CONTRACT WITH devwatch — do not "fix" the two declarations below:
 // devwatch gates .go events through depfind ownership, but ONLY for handlers
@@ -44,35 +47,45 @@ func (am *AssetMin) NewSSRFileWatcher(onBrowserReload func() error) *SSRFileWatc
 }
 
 func (w *SSRFileWatcher) MainInputFileRelativePath() string { return "go.mod" }
-func (w *SSRFileWatcher) SupportedExtensions() []string     { return []string{".go"} }
-func (w *SSRFileWatcher) UnobservedFiles() []string         { return nil }
+func (w *SSRFileWatcher) SupportedExtensions() []string {
+	return []string{".go", ".mod", ".sum", ".work"}
+}
+func (w *SSRFileWatcher) UnobservedFiles() []string { return nil }
 
-// NewFileEvent routes a .go event to the correct action.
+// NewFileEvent routes a .go event to the correct action. Removing an asset
+// source (or renaming it away: the event names the old path) re-extracts its
+// module, or removes the module (RemoveSSRModule) when no source is left;
+// removing or renaming a directory modules were loaded from removes them.
+// Under Config.EventDebounce the actions are queued with the file events.
 func (w *SSRFileWatcher) NewFileEvent(fileName, extension, filePath, event string) error {
 	moduleDir := filepath.Dir(filePath)
+	removed := event == "remove" || event == "delete" || event == "rename"
 
 	switch {
+	case slices.Contains(goModuleFiles, fileName):
+		// The browser reloads once the rescan has been applied.
+		w.am.scheduleRescan(w.browserReload)
+		return nil
+	case removed && slices.Contains(ssrTextAssetFiles, fileName):
+		return w.am.queueEvents(&eventBatch{modules: map[string]moduleEvent{moduleDir: moduleSourceRemoved}, reload: w.browserReload})
+	case removed && w.am.hasModulesIn(filePath):
+		// A module directory: the path is gone, so only the registry can tell.
+		return w.am.queueEvents(&eventBatch{modules: map[string]moduleEvent{filePath: moduleDirRemoved}, reload: w.browserReload})
 	case slices.Contains(ssrTextAssetFiles, fileName):
-		if err := w.am.ReloadSSRModule(moduleDir); err != nil {
-			w.am.Logger("SSR hot reload error:", moduleDir, err)
-			return err
-		}
+		return w.am.queueEvents(&eventBatch{modules: map[string]moduleEvent{moduleDir: moduleChanged}, reload: w.browserReload})
 	case fileName == imageAssetFile:
 		if w.am.imageProcessor == nil {
 			return nil
 		}
-		if err := w.am.imageProcessor.ReloadModule(moduleDir); err != nil {
-			w.am.Logger("image hot reload error:", moduleDir, err)
-			return err
-		}
-	default:
-		return nil
+		return w.am.queueEvents(&eventBatch{images: map[string]bool{moduleDir: true}, reload: w.browserReload})
 	}
+	return nil
+}
 
+func (w *SSRFileWatcher) browserReload() {
 	if w.onBrowserReload != nil {
 		if err := w.onBrowserReload(); err != nil {
 			w.am.Logger("browser reload error:", err)
 		}
 	}
-	return nil
 }
@@ -1,6 +1,7 @@
 package assetmin
 
 import (
+	"slices"
 	"sort"
 	"strings"
 
@@ -16,20 +17,27 @@ func NewFaviconSvgHandler(ac *Config, filename string) *asset {
 	return newAssetFile(filename, "image/svg+xml", ac, nil)
 }
 
-// renderSpriteNoLock combines every module's sprite into one, keeping the
+// spriteIcon is one icon of the combined sprite and the module that won its id.
+type spriteIcon struct {
+	def      sprite.Definition
+	module   string
+	shadowed []string // later modules declaring the same id, dropped
+}
+
+// spriteIconsNoLock resolves every module's icons into one list, keeping the
 // first occurrence of a given icon id (sorted by module name for a stable,
 // deterministic order across scans). Reads typed Definitions via Icons() —
 // never re-parses Sprite.String()'s markup — so this stays correct
 // independent of how sprite.go formats its output.
-func (c *AssetMin) renderSpriteNoLock() string {
+func (c *AssetMin) spriteIconsNoLock() []spriteIcon {
 	var keys []string
 	for k := range c.moduleSprites {
 		keys = append(keys, k)
 	}
 	sort.Strings(keys)
 
-	seen := make(map[string]bool)
-	var deduped []sprite.Definition
+	seen := make(map[string]int)
+	var icons []spriteIcon
 
 	for _, k := range keys {
 		s := c.moduleSprites[k]
@@ -38,12 +46,24 @@ func (c *AssetMin) renderSpriteNoLock() string {
 		}
 		for _, def := range s.Icons() {
 			id := def.Icon.ID()
-			if !seen[id] {
-				seen[id] = true
-				deduped = append(deduped, def)
+			if i, ok := seen[id]; ok {
+				icons[i].shadowed = append(icons[i].shadowed, k)
+				continue
 			}
+			seen[id] = len(icons)
+			icons = append(icons, spriteIcon{def: def, module: k})
 		}
 	}
+	return icons
+}
+
+// renderSpriteNoLock combines every module's sprite into one (see spriteIconsNoLock).
+func (c *AssetMin) renderSpriteNoLock() string {
+	icons := c.spriteIconsNoLock()
+	deduped := make([]sprite.Definition, len(icons))
+	for i, icon := range icons {
+		deduped[i] = icon.def
+	}
 
 	out := sprite.NewSprite(deduped...).String()
 	if out == "" {
@@ -128,6 +148,20 @@ func (c *AssetMin) addIconFile(id, content string) error {
 	return nil
 }
 
+// removeIconFile drops the icon added from a .svg file, if any.
+func (c *AssetMin) removeIconFile(id string) {
+	c.spriteMu.Lock()
+	defer c.spriteMu.Unlock()
+
+	s, ok := c.moduleSprites["_manual"]
+	if !ok {
+		return
+	}
+	icons := slices.DeleteFunc(s.Icons(), func(d sprite.Definition) bool { return string(d.Icon) == id })
+	c.moduleSprites["_manual"] = sprite.NewSprite(icons...)
+	c.spriteSvgHandler.InvalidateCache()
+}
+
 func (c *AssetMin) checkIconID(id string) error {
 	current := c.renderSpriteNoLock()
 	if strings.Contains(current, "id=\""+id+"\"") || strings.Contains(current, "id='"+id+"'") {
@@ -0,0 +1,64 @@
+//go:build !wasm
+
+package assetmin_test
+
+import (
+	"testing"
+
+	"github.com/tinywasm/assetmin"
+	"github.com/tinywasm/svg/sprite"
+)
+
+func iconSprite(id string) *sprite.Sprite {
+	s := sprite.NewSprite()
+	s.AddRaw(id, `<path d="M0 0h24v24H0z"/>`, "0 0 24 24")
+	return s
+}
+
+func TestComposition_ExplainsOrderIconsAndRootCSS(t *testing.T) {
+	am := newTestEnv(t, &assetmin.Config{RootDir: t.TempDir()}).loadSSR(&testExtractor{all: []*assetmin.SSRAssets{
+		{ModuleName: "github.com/tinywasm/css", RootCSS: ":root{--c:red}", IsFramework: true},
+		{ModuleName: "example.com/b", CSS: ".b{color:blue}", Icons: iconSprite("icon-x")},
+		{ModuleName: "example.com/a", CSS: ".a{color:red}", Icons: iconSprite("icon-x")},
+		{ModuleName: "example.com/app", RootCSS: ":root{--c:blue}", CSS: ".app{margin:0}", IsRoot: true},
+	}}).AssetsHandler
+
+	comp := am.Composition()
+
+	var css *assetmin.AssetComposition
+	for i := range comp.Assets {
+		if comp.Assets[i].Name == "style.css" {
+			css = &comp.Assets[i]
+		}
+	}
+	if css == nil {
+		t.Fatalf("composition missing style.css: %+v", comp.Assets)
+	}
+	var order []string
+	for _, c := range css.Contributors {
+		if c.Source != "" {
+			order = append(order, c.Slot+":"+c.Source)
+		}
+	}
+	want := []string{"open:example.com/app", "middle:example.com/a", "middle:example.com/b", "close:example.com/app"}
+	if len(order) != len(want) {
+		t.Fatalf("style.css contributors = %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Errorf("contributor %d = %s, want %s", i, order[i], want[i])
+		}
+	}
+
+	if len(comp.Icons) != 1 || comp.Icons[0].Module != "example.com/a" ||
+		len(comp.Icons[0].Shadowed) != 1 || comp.Icons[0].Shadowed[0] != "example.com/b" {
+		t.Errorf("icons = %+v, want icon-x won by example.com/a over example.com/b", comp.Icons)
+	}
+
+	if comp.RootCSS.Applied != "example.com/app" || comp.RootCSS.Framework != "github.com/tinywasm/css" {
+		t.Errorf("root css = %+v", comp.RootCSS)
+	}
+	if comp.Fonts != nil {
+		t.Errorf("no fonts declared, got %+v", comp.Fonts)
+	}
+}
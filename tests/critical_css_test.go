@@ -0,0 +1,111 @@
+//go:build !wasm
+
+package assetmin_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tinywasm/assetmin"
+)
+
+// criticalHead returns the <head> of the cached index HTML.
+func criticalHead(t *testing.T, am *assetmin.AssetMin) string {
+	t.Helper()
+	if err := am.RegenerateHTMLCache(); err != nil {
+		t.Fatal(err)
+	}
+	html := string(am.GetCachedHTML())
+	end := strings.Index(html, "</head>")
+	if end == -1 {
+		t.Fatalf("no </head> in index:\n%s", html)
+	}
+	return html[:end]
+}
+
+func TestCriticalCSS_InlinesRulesUsedByModuleHTML(t *testing.T) {
+	am := assetmin.NewAssetMin(&assetmin.Config{OutputDir: t.TempDir(), CriticalCSS: true})
+
+	if err := am.UpdateSSRModule("example.com/card",
+		".card{color:red}.unused{color:blue}#hero{margin:0}@media (min-width:600px){.card{padding:1px}.other{padding:2px}}",
+		nil, `<section class="card big" id="hero">Card</section>`, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	head := criticalHead(t, am)
+
+	if !strings.Contains(head, "<style>") {
+		t.Fatalf("critical CSS must be inlined in <head>:\n%s", head)
+	}
+	for _, want := range []string{".card{color:red}", "#hero{margin:0}", "padding:1px"} {
+		if !strings.Contains(head, want) {
+			t.Errorf("critical CSS missing %q:\n%s", want, head)
+		}
+	}
+	for _, unwanted := range []string{".unused", ".other"} {
+		if strings.Contains(head, unwanted) {
+			t.Errorf("critical CSS must not contain %q:\n%s", unwanted, head)
+		}
+	}
+	if !strings.Contains(head, `rel="preload"`) || !strings.Contains(head, "<noscript>") {
+		t.Errorf("full stylesheet must be loaded asynchronously with a noscript fallback:\n%s", head)
+	}
+}
+
+func TestCriticalCSS_RecomputedWhenCSSChanges(t *testing.T) {
+	am := assetmin.NewAssetMin(&assetmin.Config{OutputDir: t.TempDir(), CriticalCSS: true})
+
+	if err := am.UpdateSSRModule("example.com/card", ".card{color:red}", nil, `<div class="card"></div>`, nil); err != nil {
+		t.Fatal(err)
+	}
+	if head := criticalHead(t, am); !strings.Contains(head, "color:red") {
+		t.Fatalf("initial critical CSS missing:\n%s", head)
+	}
+
+	if err := am.UpdateSSRModule("example.com/card", ".card{color:green}", nil, `<div class="card"></div>`, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := am.GetMinifiedCSS(); err != nil {
+		t.Fatal(err)
+	}
+
+	head := criticalHead(t, am)
+	if !strings.Contains(head, "color:green") || strings.Contains(head, "color:red") {
+		t.Errorf("critical CSS not recomputed after the CSS changed:\n%s", head)
+	}
+}
+
+func TestCriticalCSS_DisabledByDefault(t *testing.T) {
+	am := assetmin.NewAssetMin(&assetmin.Config{OutputDir: t.TempDir()})
+
+	if err := am.UpdateSSRModule("example.com/card", ".card{color:red}", nil, `<div class="card"></div>`, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	head := criticalHead(t, am)
+	if strings.Contains(head, "<style>") {
+		t.Errorf("critical CSS must be opt-in:\n%s", head)
+	}
+	if !strings.Contains(head, `rel="stylesheet"`) {
+		t.Errorf("default head must link the stylesheet:\n%s", head)
+	}
+}
+
+func TestCriticalCSS_RebasesRelativeURLsOnThePrefix(t *testing.T) {
+	am := assetmin.NewAssetMin(&assetmin.Config{OutputDir: t.TempDir(), CriticalCSS: true, AssetsURLPrefix: "/static/"})
+
+	css := `.hero{background:url("img/bg.png")}.up{background:url(../up.png)}.abs{background:url(/abs.png)}.cdn{background:url(https://cdn.example.com/x.png)}`
+	if err := am.UpdateSSRModule("example.com/hero", css, nil, `<div class="hero up abs cdn"></div>`, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	head := criticalHead(t, am)
+	for _, want := range []string{"/static/img/bg.png", "url(/up.png)", "url(/abs.png)", "url(https://cdn.example.com/x.png)"} {
+		if !strings.Contains(head, want) {
+			t.Errorf("inlined critical CSS must hold %q:\n%s", want, head)
+		}
+	}
+	if strings.Contains(head, `url(img/`) || strings.Contains(head, `url("img/`) {
+		t.Errorf("a relative url() was inlined as is:\n%s", head)
+	}
+}
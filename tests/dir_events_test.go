@@ -0,0 +1,102 @@
+//go:build !wasm
+
+package assetmin_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tinywasm/assetmin"
+)
+
+const dirIcon = `<svg viewBox="0 0 24 24"><path d="M0 0h24v24H0z"/></svg>`
+
+// widgetFiles are root/keep.css plus a widgets directory holding a
+// stylesheet, a script and an icon.
+var widgetFiles = map[string]string{
+	"keep.css":              ".keep{color:red}",
+	"widgets/card.css":      ".card{color:red}",
+	"widgets/card.js":       "console.log('card')",
+	"widgets/card-icon.svg": dirIcon,
+}
+
+func TestDirEvents_RemoveDropsEverythingBelow(t *testing.T) {
+	env := newTestEnv(t, &assetmin.Config{}).writeFiles(widgetFiles).fileEvent("create", "keep.css", "widgets")
+	am, dir := env.AssetsHandler, filepath.Join(env.BaseDir, "widgets")
+	if !am.ContainsCSS(".card") || !am.ContainsJS("card") || !am.HasIcon("card-icon.svg") {
+		t.Fatal("precondition: directory create did not read every asset file")
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := am.NewFileEvent("widgets", "", dir, "remove"); err != nil {
+		t.Fatalf("directory remove: %v", err)
+	}
+	if am.ContainsCSS(".card") || am.ContainsJS("card") || am.HasIcon("card-icon.svg") {
+		t.Error("entries below the removed directory still served")
+	}
+	if !am.ContainsCSS(".keep") {
+		t.Error("entry outside the removed directory dropped")
+	}
+}
+
+func TestDirEvents_RenameMovesEverythingBelow(t *testing.T) {
+	// The destination sorts after the source, then before it.
+	for _, dest := range []string{"parts", "a-widgets"} {
+		env := newTestEnv(t, &assetmin.Config{}).writeFiles(widgetFiles).fileEvent("create", "keep.css", "widgets")
+		am, dir := env.AssetsHandler, filepath.Join(env.BaseDir, "widgets")
+
+		moved := filepath.Join(filepath.Dir(dir), dest)
+		if err := os.Rename(dir, moved); err != nil {
+			t.Fatal(err)
+		}
+		if err := am.NewFileRenameEvent(dir, moved); err != nil {
+			t.Fatalf("%s: NewFileRenameEvent: %v", dest, err)
+		}
+
+		css, js := outputString(t, am, "style.css"), outputString(t, am, "script.js")
+		if strings.Count(css, ".card") != 1 || strings.Count(js, "card") != 1 {
+			t.Errorf("%s: moved files not served exactly once:\n%s\n%s", dest, css, js)
+		}
+		if !am.HasIcon("card-icon.svg") {
+			t.Errorf("%s: icon of the moved directory lost", dest)
+		}
+
+		// The old path is gone: removing it again changes nothing.
+		if err := am.NewFileEvent("widgets", "", dir, "remove"); err != nil {
+			t.Fatal(err)
+		}
+		if !am.ContainsCSS(".card") {
+			t.Errorf("%s: entries at the new path dropped by an event on the old one", dest)
+		}
+	}
+}
+
+func TestDirEvents_RemoveSupersedesQueuedEventsBelow(t *testing.T) {
+	env := newTestEnv(t, &assetmin.Config{EventDebounce: time.Hour}).writeFiles(widgetFiles).fileEvent("create", "keep.css", "widgets")
+	am, dir := env.AssetsHandler, filepath.Join(env.BaseDir, "widgets")
+
+	path := filepath.Join(dir, "card.css")
+	if err := am.NewFileEvent("card.css", ".css", path, "write"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := am.NewFileEvent("widgets", "", dir, "remove"); err != nil {
+		t.Fatal(err)
+	}
+	if err := am.FlushEvents(); err != nil {
+		t.Fatalf("FlushEvents: %v", err)
+	}
+	if am.ContainsCSS(".card") || am.ContainsJS("card") {
+		t.Error("entries below the removed directory still served")
+	}
+	if !am.ContainsCSS(".keep") {
+		t.Error("entry outside the removed directory dropped")
+	}
+}
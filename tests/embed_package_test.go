@@ -0,0 +1,112 @@
+//go:build !wasm
+
+package assetmin_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tinywasm/assetmin"
+	"github.com/tinywasm/js"
+)
+
+func TestGenerateEmbedPackage(t *testing.T) {
+	am := newTestEnv(t, &assetmin.Config{
+		RootDir:         t.TempDir(),
+		AssetsURLPrefix: "/static/",
+	}).loadSSR(&testExtractor{all: []*assetmin.SSRAssets{{
+		ModuleName: "app",
+		CSS:        ".app{color:red}",
+		JS:         []*js.Script{{Content: "console.log('app')"}},
+	}}}).AssetsHandler
+
+	mod := t.TempDir()
+	dir := filepath.Join(mod, "webassets")
+	if err := am.GenerateEmbedPackage(dir, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	css, err := os.ReadFile(filepath.Join(dir, "assets", "style.css"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := am.GetMinifiedCSS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(css) != string(want) {
+		t.Errorf("embedded style.css = %q, want %q", css, want)
+	}
+	src, err := os.ReadFile(filepath.Join(dir, "assets_embed.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"package webassets", "//go:embed assets", `"style.css":`, `"/static/style.css"`, "public, max-age=31536000, immutable"} {
+		if !strings.Contains(string(src), s) {
+			t.Errorf("generated source lacks %q", s)
+		}
+	}
+
+	// A stale file from a previous generation is not embedded again.
+	stale := filepath.Join(dir, "assets", "old.css")
+	if err := os.WriteFile(stale, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := am.GenerateEmbedPackage(dir, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("stale embedded file survived regeneration: %v", err)
+	}
+
+	if err := am.GenerateEmbedPackage(dir, "web-assets"); err == nil {
+		t.Error("invalid package name accepted")
+	}
+
+	// The generated package compiles and serves what it embeds.
+	goBin, err := exec.LookPath("go")
+	if err != nil || testing.Short() {
+		t.Skip("go toolchain not available")
+	}
+	files := map[string]string{
+		"go.mod": "module embedcheck\n\ngo 1.22\n",
+		"webassets/handler_test.go": `package webassets
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, URL("style.css"), nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "text/css" {
+		t.Fatalf("status %d, type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if rec.Body.Len() != int(Manifest["style.css"].Size) {
+		t.Fatalf("served %d bytes, manifest says %d", rec.Body.Len(), Manifest["style.css"].Size)
+	}
+	rec = httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/static/missing.css", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("missing asset: status %d", rec.Code)
+	}
+}
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(mod, filepath.FromSlash(name)), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cmd := exec.Command(goBin, "test", "./...")
+	cmd.Dir = mod
+	cmd.Env = append(os.Environ(), "GOWORK=off", "GOFLAGS=", "GOPROXY=off")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go test in generated package: %v\n%s", err, out)
+	}
+}
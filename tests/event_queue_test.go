@@ -0,0 +1,113 @@
+//go:build !wasm
+
+package assetmin_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tinywasm/assetmin"
+)
+
+func writeAsset(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEventQueue_CoalescesFilesIntoOneRegeneration(t *testing.T) {
+	sink := newCountingSink()
+	env := newTestEnv(t, &assetmin.Config{OutputSink: sink, EventDebounce: time.Hour})
+	am := env.AssetsHandler
+	if err := am.FlushToDisk(); err != nil {
+		t.Fatal(err)
+	}
+	before := sink.count("style.css")
+
+	// A checkout touches several stylesheets, one of them twice.
+	env.writeFiles(map[string]string{"a.css": ".a{color:red}", "b.css": ".b{color:red}", "c.css": ".c{color:red}"}).
+		fileEvent("create", "a.css", "b.css", "c.css")
+	env.writeFiles(map[string]string{"a.css": ".a{color:blue}"}).fileEvent("write", "a.css")
+	if am.ContainsCSS(".b") {
+		t.Fatal("queued events applied before the window expired")
+	}
+
+	if err := am.FlushEvents(); err != nil {
+		t.Fatalf("FlushEvents: %v", err)
+	}
+	for _, want := range []string{".a{color:blue}", ".b{color:red}", ".c{color:red}"} {
+		if !am.ContainsCSS(want) {
+			t.Errorf("style.css missing %s", want)
+		}
+	}
+	if am.ContainsCSS(".a{color:red}") {
+		t.Error("superseded event for a.css applied")
+	}
+	if got := sink.count("style.css") - before; got != 1 {
+		t.Errorf("style.css written %d times, want 1", got)
+	}
+}
+
+func TestEventQueue_AppliesWhenWindowExpires(t *testing.T) {
+	am := newTestEnv(t, &assetmin.Config{EventDebounce: 20 * time.Millisecond}).
+		writeFiles(map[string]string{"main.js": "console.log('queued')"}).
+		fileEvent("create", "main.js").
+		AssetsHandler
+	waitForSSRLoad(t, am, 5*time.Second, "queued events were not applied")
+	if !am.ContainsJS("queued") {
+		t.Error("script.js missing the queued file")
+	}
+}
+
+func TestEventQueue_CoalescesModuleReloads(t *testing.T) {
+	am, moduleDir, ex, reloads := ssrWatcherEnv(t, "#111111")
+	am.EventDebounce = time.Hour
+
+	w := am.NewSSRFileWatcher(func() error { *reloads++; return nil })
+	for _, marker := range []string{"#222222", "#333333", "#444444"} {
+		writeCSSGo(t, moduleDir, marker)
+		if err := w.NewFileEvent("css.go", ".go", filepath.Join(moduleDir, "css.go"), "write"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if ex.calls != 0 {
+		t.Fatalf("module extracted %d times before the window expired", ex.calls)
+	}
+
+	if err := am.FlushEvents(); err != nil {
+		t.Fatalf("FlushEvents: %v", err)
+	}
+	if ex.calls != 1 {
+		t.Errorf("ExtractModule calls = %d, want 1", ex.calls)
+	}
+	if !am.ContainsCSS("#444444") {
+		t.Error("latest css.go not served")
+	}
+	if *reloads != 1 {
+		t.Errorf("browser reloads = %d, want 1", *reloads)
+	}
+}
+
+func TestEventQueue_CloseDropsQueuedEvents(t *testing.T) {
+	am := newTestEnv(t, &assetmin.Config{EventDebounce: 20 * time.Millisecond}).
+		writeFiles(map[string]string{"late.css": ".late{color:red}"}).
+		fileEvent("create", "late.css").
+		AssetsHandler
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := am.Close(ctx); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if am.ContainsCSS(".late") {
+		t.Error("queued event applied after Close")
+	}
+	if err := am.FlushEvents(); err != assetmin.ErrClosed {
+		t.Errorf("FlushEvents after Close = %v, want ErrClosed", err)
+	}
+}
@@ -0,0 +1,92 @@
+//go:build !wasm
+
+package assetmin_test
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/tinywasm/assetmin"
+	"github.com/tinywasm/js"
+)
+
+func TestFlush_SkipsUnchangedOutputs(t *testing.T) {
+	out := t.TempDir()
+	am := assetmin.NewAssetMin(&assetmin.Config{OutputDir: out})
+	if err := am.UpdateSSRModule("example.com/ui", ".ui{color:red}", []*js.Script{{Content: "console.log(1)"}}, "", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := am.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(first.Written, am.GetMainCssPath()) || len(first.Unchanged) != 0 {
+		t.Fatalf("first flush must write everything: %+v", first)
+	}
+
+	// Age every output so a rewrite would be visible in its mtime.
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	for _, path := range first.Written {
+		if err := os.Chtimes(path, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	second, err := am.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(second.Written) != 0 || len(second.Unchanged) != len(first.Written) {
+		t.Errorf("nothing changed, nothing must be written: %+v", second)
+	}
+	if info, _ := os.Stat(am.GetMainJsPath()); !info.ModTime().Equal(old) {
+		t.Errorf("script.js mtime = %v, want untouched %v", info.ModTime(), old)
+	}
+
+	if err := am.UpdateSSRModule("example.com/ui", ".ui{color:blue}", []*js.Script{{Content: "console.log(1)"}}, "", nil); err != nil {
+		t.Fatal(err)
+	}
+	third, err := am.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(third.Written, am.GetMainCssPath()) {
+		t.Errorf("changed style.css must be written: %+v", third)
+	}
+	if !slices.Contains(third.Unchanged, am.GetMainJsPath()) {
+		t.Errorf("unchanged script.js must be skipped: %+v", third)
+	}
+}
+
+func TestFlush_ComparesAgainstDiskAfterRestart(t *testing.T) {
+	out := t.TempDir()
+	build := func() *assetmin.AssetMin {
+		am := assetmin.NewAssetMin(&assetmin.Config{OutputDir: out})
+		if err := am.UpdateSSRModule("example.com/ui", ".ui{color:red}", []*js.Script{{Name: "sw.js", Content: "self.x=1"}}, "", nil); err != nil {
+			t.Fatal(err)
+		}
+		return am
+	}
+	if _, err := build().Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	am := build()
+	if err := am.UpdateSSRModule("example.com/ui", ".ui{color:red}", nil, "", nil); err != nil {
+		t.Fatal(err)
+	}
+	result, err := am.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slices.Contains(result.Written, am.GetMainCssPath()) {
+		t.Errorf("style.css already on disk must not be rewritten: %+v", result)
+	}
+	if !slices.Equal(result.Removed, []string{filepath.Join(out, "sw.js")}) {
+		t.Errorf("removed = %v, want sw.js", result.Removed)
+	}
+}
@@ -0,0 +1,243 @@
+//go:build !wasm
+
+package assetmin_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tinywasm/assetmin"
+)
+
+// contextExtractor blocks ExtractAllContext until its context is cancelled.
+type contextExtractor struct {
+	started chan struct{}
+}
+
+func (e *contextExtractor) ExtractModule(moduleDir string) (*assetmin.SSRAssets, error) {
+	return nil, nil
+}
+
+func (e *contextExtractor) ExtractAll() ([]*assetmin.SSRAssets, error) {
+	return nil, errors.New("not cancellable")
+}
+
+func (e *contextExtractor) ExtractModuleContext(ctx context.Context, moduleDir string) (*assetmin.SSRAssets, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (e *contextExtractor) ExtractAllContext(ctx context.Context) ([]*assetmin.SSRAssets, error) {
+	close(e.started)
+	<-ctx.Done()
+	return []*assetmin.SSRAssets{{ModuleName: "late", CSS: ".late{color:red}"}}, ctx.Err()
+}
+
+func TestClose_CancelsRetriesAndWaits(t *testing.T) {
+	am := assetmin.NewAssetMin(&assetmin.Config{OutputDir: t.TempDir()})
+	am.SetSSRExtractor(&testExtractor{err: errors.New("broken module")})
+	am.LoadSSRModules()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	start := time.Now()
+	if err := am.Close(ctx); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Close waited %v for the retry backoff", elapsed)
+	}
+	if state, err := am.Readiness(); state != assetmin.LoadFailed || !errors.Is(err, context.Canceled) {
+		t.Errorf("after Close: %v, %v", state, err)
+	}
+}
+
+func TestClose_StopsExtractionAndWritesNothing(t *testing.T) {
+	outDir := t.TempDir()
+	am := assetmin.NewAssetMin(&assetmin.Config{OutputDir: outDir})
+	if err := am.FlushToDisk(); err != nil {
+		t.Fatal(err)
+	}
+	before, err := os.ReadFile(filepath.Join(outDir, "style.css"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ext := &contextExtractor{started: make(chan struct{})}
+	am.SetSSRExtractor(ext)
+	am.LoadSSRModules()
+	<-ext.started
+	if err := am.Close(context.Background()); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	after, err := os.ReadFile(filepath.Join(outDir, "style.css"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(after) != string(before) {
+		t.Errorf("style.css written after Close: %q", after)
+	}
+}
+
+// gatedSink holds the first WriteFile until release is closed, and counts
+// the writes made once closed is set.
+type gatedSink struct {
+	assetmin.OutputSink
+	entered, release chan struct{}
+	once             sync.Once
+	mu               sync.Mutex
+	closed           bool
+	late             int
+}
+
+func (s *gatedSink) WriteFile(name string, content []byte) error {
+	s.once.Do(func() {
+		close(s.entered)
+		<-s.release
+	})
+	s.mu.Lock()
+	if s.closed {
+		s.late++
+	}
+	s.mu.Unlock()
+	return s.OutputSink.WriteFile(name, content)
+}
+
+func TestClose_WaitsForFlushInProgress(t *testing.T) {
+	sink := &gatedSink{OutputSink: assetmin.NewMemorySink(), entered: make(chan struct{}), release: make(chan struct{})}
+	am := assetmin.NewAssetMin(&assetmin.Config{OutputDir: t.TempDir(), OutputSink: sink})
+	flushed := make(chan error, 1)
+	go func() {
+		_, err := am.Flush()
+		flushed <- err
+	}()
+	<-sink.entered
+
+	closed := make(chan error, 1)
+	go func() {
+		err := am.Close(context.Background())
+		sink.mu.Lock()
+		sink.closed = true
+		sink.mu.Unlock()
+		closed <- err
+	}()
+	select {
+	case <-closed:
+		t.Fatal("Close returned while a flush was writing")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(sink.release)
+
+	if err := <-closed; err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := <-flushed; !errors.Is(err, context.Canceled) {
+		t.Errorf("Flush: %v, want it cancelled by Close", err)
+	}
+	if sink.late != 0 {
+		t.Errorf("%d writes after Close returned", sink.late)
+	}
+}
+
+func TestClose_RejectsNewWork(t *testing.T) {
+	dir := t.TempDir()
+	am := assetmin.NewAssetMin(&assetmin.Config{OutputDir: t.TempDir()})
+	if err := am.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	file := filepath.Join(dir, "a.css")
+	os.WriteFile(file, []byte(".a{}"), 0644)
+	if err := am.NewFileEvent("a.css", ".css", file, "create"); !errors.Is(err, assetmin.ErrClosed) {
+		t.Errorf("NewFileEvent: %v", err)
+	}
+	if err := am.UpdateSSRModule("m", ".m{}", nil, "", nil); !errors.Is(err, assetmin.ErrClosed) {
+		t.Errorf("UpdateSSRModule: %v", err)
+	}
+	if err := am.ReloadSSRModule(dir); !errors.Is(err, assetmin.ErrClosed) {
+		t.Errorf("ReloadSSRModule: %v", err)
+	}
+	if err := am.FlushToDisk(); !errors.Is(err, assetmin.ErrClosed) {
+		t.Errorf("FlushToDisk: %v", err)
+	}
+	am.LoadSSRModules()
+	if state, _ := am.Readiness(); state == assetmin.LoadLoading {
+		t.Error("a load started after Close")
+	}
+}
+
+func TestClose_ReturnsWhenContextExpires(t *testing.T) {
+	gate := &testExtractor{release: make(chan struct{})} // ignores cancellation
+	defer close(gate.release)
+	am := assetmin.NewAssetMin(&assetmin.Config{OutputDir: t.TempDir()})
+	am.SetSSRExtractor(gate)
+	am.LoadSSRModules()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := am.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Close: %v, want deadline exceeded", err)
+	}
+}
+
+func TestWaitForSSRLoadContext_ReportsUnfinishedLoad(t *testing.T) {
+	gate := &testExtractor{release: make(chan struct{}), all: []*assetmin.SSRAssets{{ModuleName: "app", CSS: ".app{color:red}"}}}
+	am := assetmin.NewAssetMin(&assetmin.Config{OutputDir: t.TempDir()})
+	am.SetSSRExtractor(gate)
+	am.LoadSSRModules()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := am.WaitForSSRLoadContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("during the load: %v, want deadline exceeded", err)
+	}
+	am.WaitForSSRLoad(20 * time.Millisecond) // returns at the timeout
+
+	close(gate.release)
+	if err := am.WaitForSSRLoadContext(context.Background()); err != nil {
+		t.Errorf("after the load: %v", err)
+	}
+}
+
+func TestLoadSSRModulesContext_Cancelled(t *testing.T) {
+	am := assetmin.NewAssetMin(&assetmin.Config{OutputDir: t.TempDir()})
+	ext := &contextExtractor{started: make(chan struct{})}
+	am.SetSSRExtractor(ext)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	am.LoadSSRModulesContext(ctx)
+	<-ext.started
+	cancel()
+	waitForSSRLoad(t, am, time.Second, "cancelled load did not finish")
+	if state, err := am.Readiness(); state != assetmin.LoadFailed || !errors.Is(err, context.Canceled) {
+		t.Errorf("after cancel: %v, %v", state, err)
+	}
+	css, _ := am.GetMinifiedCSS()
+	if len(css) > 0 {
+		t.Errorf("cancelled load was applied: %q", css)
+	}
+}
+
+func TestFlushContext_Cancelled(t *testing.T) {
+	outDir := t.TempDir()
+	am := assetmin.NewAssetMin(&assetmin.Config{OutputDir: outDir})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result, err := am.FlushContext(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("FlushContext: %v", err)
+	}
+	if len(result.Written) != 0 {
+		t.Errorf("written after cancel: %v", result.Written)
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "asset-manifest.json")); !os.IsNotExist(err) {
+		t.Errorf("manifest written after cancel: %v", err)
+	}
+}
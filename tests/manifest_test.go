@@ -0,0 +1,88 @@
+//go:build !wasm
+
+package assetmin_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/tinywasm/assetmin"
+	"github.com/tinywasm/js"
+)
+
+func TestManifest_WrittenByFlushToDisk(t *testing.T) {
+	am := assetmin.NewAssetMin(&assetmin.Config{OutputDir: t.TempDir(), AssetsURLPrefix: "/static/"})
+
+	scripts := []*js.Script{{Content: "console.log('ui')"}, {Name: "sw.js", Content: "self.x=1"}}
+	if err := am.UpdateSSRModule("example.com/ui", ".ui{color:red}", scripts, "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := am.FlushToDisk(); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := assetmin.ReadManifest(am.OutputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"style.css", "script.js", "icons.svg", "favicon.svg", "index.html", "sw.js"} {
+		e, ok := m.Lookup(name)
+		if !ok {
+			t.Errorf("manifest missing %s: %+v", name, m.Assets)
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(am.OutputDir, filepath.FromSlash(e.Path)))
+		if err != nil {
+			t.Errorf("%s: path %q not on disk: %v", name, e.Path, err)
+			continue
+		}
+		sum := sha256.Sum256(content)
+		if e.Hash != hex.EncodeToString(sum[:]) {
+			t.Errorf("%s: hash does not match the file on disk", name)
+		}
+		if e.Size != int64(len(content)) {
+			t.Errorf("%s: size = %d, file has %d bytes", name, e.Size, len(content))
+		}
+	}
+
+	css, _ := m.Lookup("style.css")
+	if css.URL != "/static/style.css" || css.MediaType != "text/css" {
+		t.Errorf("style.css entry = %+v", css)
+	}
+	if !slices.Contains(css.Modules, "example.com/ui") {
+		t.Errorf("style.css modules = %v, want example.com/ui", css.Modules)
+	}
+	if m.URL("script.js") != "/static/script.js" {
+		t.Errorf("URL(script.js) = %q", m.URL("script.js"))
+	}
+	if sw, _ := m.Lookup("sw.js"); !slices.Contains(sw.Modules, "example.com/ui:sw.js") {
+		t.Errorf("sw.js modules = %v", sw.Modules)
+	}
+}
+
+func TestManifest_MatchesInMemoryOutputs(t *testing.T) {
+	am := assetmin.NewAssetMin(&assetmin.Config{OutputDir: t.TempDir()})
+	am.InjectCSS("x", ".x{color:red}")
+
+	m, err := am.Manifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	css, err := am.GetMinifiedCSS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, ok := m.Lookup("style.css")
+	if !ok {
+		t.Fatal("manifest missing style.css")
+	}
+	sum := sha256.Sum256(css)
+	if e.Hash != hex.EncodeToString(sum[:]) {
+		t.Error("manifest hash must match the served stylesheet")
+	}
+}
@@ -0,0 +1,120 @@
+//go:build !wasm
+
+package assetmin_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/tinywasm/assetmin"
+	"github.com/tinywasm/js"
+)
+
+func TestModules_RecordsContributions(t *testing.T) {
+	am := newTestEnv(t, &assetmin.Config{}).loadSSR(&testExtractor{all: []*assetmin.SSRAssets{
+		{ModuleName: "example.com/app", IsRoot: true, CSS: ".app{}", RootCSS: ":root{--c:red}", HTML: "<main></main>"},
+		{ModuleName: "github.com/acme/ui", CSS: ".ui{color:red}", JS: []*js.Script{{Content: "ui()"}, {Name: "sw.js", Content: "self.x=1"}}, Icons: iconSprite("icon-ui")},
+	}}).AssetsHandler
+
+	mods := am.Modules()
+	if len(mods) != 2 {
+		t.Fatalf("Modules() = %+v", mods)
+	}
+	app, ui := mods[0], mods[1]
+	if app.Name != "example.com/app" || !app.IsRoot || app.IsFramework {
+		t.Errorf("app = %+v", app)
+	}
+	if want := []string{"css", "html", "rootCSS"}; !reflect.DeepEqual(app.Kinds, want) {
+		t.Errorf("app kinds = %v, want %v", app.Kinds, want)
+	}
+	if app.Sizes["rootCSS"] != len(":root{--c:red}") || app.LoadedAt.IsZero() {
+		t.Errorf("app = %+v", app)
+	}
+	if want := []string{"css", "icons", "js", "standalone"}; !reflect.DeepEqual(ui.Kinds, want) {
+		t.Errorf("ui kinds = %v, want %v", ui.Kinds, want)
+	}
+	if ui.Sizes["js"] != len("ui()") || ui.Sizes["standalone"] != len("self.x=1") || ui.Sizes["icons"] == 0 {
+		t.Errorf("ui sizes = %v", ui.Sizes)
+	}
+	if !reflect.DeepEqual(ui.Standalone, []string{"sw.js"}) {
+		t.Errorf("ui standalone = %v", ui.Standalone)
+	}
+}
+
+func TestModules_ReloadErrorIsRecordedAndCleared(t *testing.T) {
+	dir := t.TempDir()
+	ex := &testExtractor{
+		byDir: map[string]*assetmin.SSRAssets{dir: {ModuleName: "github.com/acme/ui", CSS: ".ui{}"}},
+		errs:  map[string]error{},
+	}
+	am := newTestEnv(t, &assetmin.Config{}).loadSSR(ex).AssetsHandler
+	if err := am.ReloadSSRModule(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	ex.errs[dir] = errors.New("css.go:3: syntax error")
+	if err := am.ReloadSSRModule(dir); err == nil {
+		t.Fatal("reload error not returned")
+	}
+	mods := am.Modules()
+	if len(mods) != 1 || mods[0].Dir != dir || mods[0].Error != "css.go:3: syntax error" || mods[0].ErrorAt.IsZero() {
+		t.Fatalf("after the failed reload: %+v", mods)
+	}
+	if mods[0].Sizes["css"] == 0 {
+		t.Error("failed reload dropped the last good contribution")
+	}
+
+	delete(ex.errs, dir)
+	if err := am.ReloadSSRModule(dir); err != nil {
+		t.Fatal(err)
+	}
+	if mods := am.Modules(); mods[0].Error != "" {
+		t.Errorf("error not cleared by a successful reload: %+v", mods[0])
+	}
+}
+
+func TestModules_ReloadErrorOfUnknownDirIsNotAnEntry(t *testing.T) {
+	dir := t.TempDir()
+	ex := &testExtractor{errs: map[string]error{dir: errors.New("css.go:1: syntax error")}}
+	am := newTestEnv(t, &assetmin.Config{}).loadSSR(ex).AssetsHandler
+
+	if err := am.ReloadSSRModule(dir); err == nil {
+		t.Fatal("reload error not returned")
+	}
+	if mods := am.Modules(); len(mods) != 0 {
+		t.Errorf("Modules() = %+v, want no entry for a directory no module was loaded from", mods)
+	}
+}
+
+func TestModules_LoadModuleErrorIsRecorded(t *testing.T) {
+	ex := &retryExtractor{failCount: 1, failResult: &assetmin.ModuleError{Module: "github.com/acme/broken", Err: errors.New("does not compile")}}
+	am := newTestEnv(t, &assetmin.Config{SSRRetry: fastRetry}).loadSSR(ex).AssetsHandler
+
+	mods := am.Modules()
+	if len(mods) != 1 || mods[0].Name != "github.com/acme/broken" || mods[0].Error != "does not compile" {
+		t.Errorf("Modules() = %+v", mods)
+	}
+}
+
+func TestModules_DevRoute(t *testing.T) {
+	ex := &testExtractor{all: []*assetmin.SSRAssets{{ModuleName: "github.com/acme/ui", CSS: ".ui{}"}}}
+
+	am := newTestEnv(t, &assetmin.Config{DevMode: true, ModulesPath: "/__assetmin/modules"}).loadSSR(ex).AssetsHandler
+	ctx := invoke(newTestRouter(am), "/__assetmin/modules")
+	var got []assetmin.ModuleStatus
+	if err := json.Unmarshal(ctx.ResponseBody(), &got); err != nil {
+		t.Fatalf("%v: %s", err, ctx.ResponseBody())
+	}
+	if len(got) != 1 || got[0].Name != "github.com/acme/ui" || got[0].Sizes["css"] != len(".ui{}") {
+		t.Errorf("route body = %+v", got)
+	}
+
+	prod := newTestEnv(t, &assetmin.Config{ModulesPath: "/__assetmin/modules"}).loadSSR(ex).AssetsHandler
+	for _, route := range newTestRouter(prod).Routes() {
+		if route.Path == "/__assetmin/modules" {
+			t.Error("modules route registered outside DevMode")
+		}
+	}
+}
@@ -0,0 +1,167 @@
+//go:build !wasm
+
+package assetmin_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tinywasm/assetmin"
+	"github.com/tinywasm/js"
+)
+
+func TestFlushToDisk_RemovesOrphanedOutputs(t *testing.T) {
+	out := t.TempDir()
+	unrelated := filepath.Join(out, "robots.txt")
+	if err := os.WriteFile(unrelated, []byte("User-agent: *"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	am := assetmin.NewAssetMin(&assetmin.Config{OutputDir: out})
+	sw := []*js.Script{{Name: "sw.js", Content: "self.x=1"}}
+	if err := am.UpdateSSRModule("example.com/ui", ".ui{color:red}", sw, "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := am.FlushToDisk(); err != nil {
+		t.Fatal(err)
+	}
+	swPath := filepath.Join(out, "sw.js")
+	if _, err := os.Stat(swPath); err != nil {
+		t.Fatalf("sw.js must be written: %v", err)
+	}
+
+	// The module stops emitting sw.js.
+	if err := am.UpdateSSRModule("example.com/ui", ".ui{color:red}", nil, "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := am.FlushToDisk(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(swPath); !os.IsNotExist(err) {
+		t.Errorf("orphaned sw.js must be removed, stat err = %v", err)
+	}
+	m, err := assetmin.ReadManifest(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.Lookup("sw.js"); ok {
+		t.Error("manifest must not list a file that is no longer produced")
+	}
+	if _, err := os.Stat(unrelated); err != nil {
+		t.Errorf("files AssetMin did not write must be left alone: %v", err)
+	}
+}
+
+func TestFlushToDisk_RemovesOrphansOfPreviousRun(t *testing.T) {
+	out := t.TempDir()
+
+	first := assetmin.NewAssetMin(&assetmin.Config{OutputDir: out})
+	if err := first.UpdateSSRModule("example.com/ui", "", []*js.Script{{Name: "worker.js", Content: "self.y=1"}}, "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := first.FlushToDisk(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new process whose project no longer produces worker.js.
+	second := assetmin.NewAssetMin(&assetmin.Config{OutputDir: out})
+	if err := second.FlushToDisk(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(out, "worker.js")); !os.IsNotExist(err) {
+		t.Errorf("worker.js written by the previous run must be removed, stat err = %v", err)
+	}
+	if _, err := os.Stat(second.GetMainCssPath()); err != nil {
+		t.Errorf("current outputs must stay: %v", err)
+	}
+}
+
+func TestFileWrite_ReplacesWithoutLeftovers(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "script.js")
+	if err := os.WriteFile(path, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, content := range []string{"console.log(1)", "console.log(2)"} {
+		if err := assetmin.FileWrite(path, *bytes.NewBufferString(content)); err != nil {
+			t.Fatal(err)
+		}
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != content {
+			t.Errorf("content = %q, want %q", got, content)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		t.Errorf("temporary files left behind: %v", names)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0644 {
+		t.Errorf("mode = %v, want 0644", info.Mode().Perm())
+	}
+}
+
+func TestFlushToDisk_KeepsProcessedImages(t *testing.T) {
+	out := t.TempDir()
+	hero := filepath.Join(out, "img", "hero.webp")
+	os.MkdirAll(filepath.Dir(hero), 0755)
+	if err := os.WriteFile(hero, []byte("RIFF"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	first := assetmin.NewAssetMin(&assetmin.Config{OutputDir: out})
+	img := &fakeImageProcessor{outputs: []string{hero}}
+	first.SetImageProcessor(img)
+	if err := first.FlushToDisk(); err != nil {
+		t.Fatal(err)
+	}
+	if m, err := assetmin.ReadManifest(out); err != nil {
+		t.Fatal(err)
+	} else if _, ok := m.Lookup("img/hero.webp"); !ok {
+		t.Fatal("manifest must list the processed image")
+	}
+
+	// The processor stops listing it.
+	img.outputs = nil
+	if err := first.FlushToDisk(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(hero); err != nil {
+		t.Fatalf("image no longer listed must be left alone: %v", err)
+	}
+
+	// A new process without a processor (eg: assetmin build) reads the
+	// manifest that lists it.
+	img.outputs = []string{hero}
+	if err := first.FlushToDisk(); err != nil {
+		t.Fatal(err)
+	}
+	second := assetmin.NewAssetMin(&assetmin.Config{OutputDir: out})
+	result, err := second.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Removed) != 0 {
+		t.Errorf("Removed = %v, want none", result.Removed)
+	}
+	if _, err := os.Stat(hero); err != nil {
+		t.Errorf("image written by the ImageProcessor must be left alone: %v", err)
+	}
+}
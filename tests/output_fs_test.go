@@ -0,0 +1,110 @@
+//go:build !wasm
+
+package assetmin_test
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/tinywasm/assetmin"
+	"github.com/tinywasm/font"
+	"github.com/tinywasm/js"
+)
+
+func TestFS_ExposesEveryOutput(t *testing.T) {
+	root := t.TempDir()
+	writeFaceFiles(t, filepath.Join(root, "fonts"), "Roboto", nil)
+
+	am := assetmin.NewAssetMin(&assetmin.Config{
+		RootDir:         root,
+		OutputDir:       t.TempDir(),
+		SourceMaps:      true,
+		WriteSourceMaps: true,
+	})
+	am.SetSSRExtractor(&fontsExtractor{assets: &assetmin.SSRAssets{
+		ModuleName: "app",
+		IsRoot:     true,
+		CSS:        ".app{color:red}",
+		JS:         []*js.Script{{Content: "console.log('app')"}, {Name: "sw.js", Content: "self.x=1"}},
+		Icons:      iconSprite("icon-app"),
+		Fonts:      font.Declare("Roboto", "fonts"),
+	}})
+	am.LoadSSRModules()
+	waitForSSRLoad(t, am, 5*time.Second, "load did not finish")
+
+	fsys := am.FS()
+	if err := fstest.TestFS(fsys,
+		"style.css", "script.js", "icons.svg", "favicon.svg", "index.html", "sw.js",
+		"style.css.map", "script.js.map", "asset-manifest.json", "Roboto-Regular.ttf",
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	css, err := fs.ReadFile(fsys, "style.css")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := am.GetMinifiedCSS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(css, want) {
+		t.Errorf("FS style.css differs from the served bundle:\n%s\n%s", css, want)
+	}
+	info, err := fs.Stat(fsys, "style.css")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != int64(len(css)) || info.ModTime().IsZero() {
+		t.Errorf("stat = size %d, mod time %v", info.Size(), info.ModTime())
+	}
+
+	srv := httptest.NewServer(http.FileServer(http.FS(fsys)))
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + "/sw.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != 200 || !bytes.Contains(body, []byte("self.x")) {
+		t.Errorf("http.FileServer over FS() = %d %q", resp.StatusCode, body)
+	}
+}
+
+func TestFS_ReusesSnapshotUntilOutputsChange(t *testing.T) {
+	am := assetmin.NewAssetMin(&assetmin.Config{RootDir: t.TempDir(), OutputDir: t.TempDir()})
+	if err := am.UpdateSSRModule("example.com/card", ".card{color:red}", nil, "", nil); err != nil {
+		t.Fatal(err)
+	}
+	fsys := am.FS()
+	first, err := fs.Stat(fsys, "style.css")
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := fs.Stat(fsys, "style.css")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !again.ModTime().Equal(first.ModTime()) || again.Size() != first.Size() {
+		t.Errorf("unchanged outputs must give the same file: %v %d, then %v %d", first.ModTime(), first.Size(), again.ModTime(), again.Size())
+	}
+
+	if err := am.UpdateSSRModule("example.com/card", ".card{color:green;margin:0}", nil, "", nil); err != nil {
+		t.Fatal(err)
+	}
+	css, err := fs.ReadFile(fsys, "style.css")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(css, []byte("green")) {
+		t.Errorf("FS must reflect the change after it was opened:\n%s", css)
+	}
+}
@@ -0,0 +1,66 @@
+//go:build !wasm
+
+package assetmin_test
+
+import (
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tinywasm/assetmin"
+	"github.com/tinywasm/font"
+	"github.com/tinywasm/js"
+)
+
+func TestOutputSink_MemoryReceivesEveryOutput(t *testing.T) {
+	root := t.TempDir()
+	out := t.TempDir()
+	writeFaceFiles(t, root+"/fonts", "Roboto", nil)
+
+	sink := assetmin.NewMemorySink()
+	am := assetmin.NewAssetMin(&assetmin.Config{RootDir: root, OutputDir: out, OutputSink: sink})
+	am.SetSSRExtractor(&fontsExtractor{assets: &assetmin.SSRAssets{
+		ModuleName: "app",
+		IsRoot:     true,
+		CSS:        ".app{color:red}",
+		JS:         []*js.Script{{Name: "sw.js", Content: "self.x=1"}},
+		Fonts:      font.Declare("Roboto", "fonts"),
+	}})
+	am.LoadSSRModules()
+	waitForSSRLoad(t, am, 5*time.Second, "load did not finish")
+
+	if err := am.FlushToDisk(); err != nil {
+		t.Fatal(err)
+	}
+
+	names := sink.Names()
+	for _, want := range []string{"style.css", "script.js", "index.html", "sw.js", "asset-manifest.json", "Roboto-Regular.ttf"} {
+		if !slices.Contains(names, want) {
+			t.Errorf("sink missing %s, has %v", want, names)
+		}
+	}
+	css, err := sink.ReadFile("style.css")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(css), ".app{color:red}") {
+		t.Errorf("style.css = %s", css)
+	}
+	if entries, _ := os.ReadDir(out); len(entries) != 0 {
+		t.Errorf("nothing must be written to OutputDir with a custom sink, found %d entries", len(entries))
+	}
+
+	// Mirrored writes and orphan cleanup go through the sink too.
+	if err := am.UpdateSSRModuleInSlot("app", ".app{color:blue}", nil, "", nil, "close"); err != nil {
+		t.Fatal(err)
+	}
+	am.RefreshJSAssets()
+	if _, err := am.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if slices.Contains(sink.Names(), "sw.js") {
+		t.Error("orphaned sw.js must be removed from the sink")
+	}
+}
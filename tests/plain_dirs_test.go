@@ -0,0 +1,135 @@
+//go:build !wasm
+
+package assetmin_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tinywasm/assetmin"
+)
+
+// legacyFiles sit in the plain web/legacy directory, created in the order of
+// legacyPaths, of a project whose modules come from newHybridExtractor.
+var (
+	legacyFiles = map[string]string{
+		"web/legacy/base.css": ".legacy{color:red}",
+		"web/legacy/old.js":   "console.log('legacy')",
+		"web/legacy/logo.svg": dirIcon,
+	}
+	legacyPaths = []string{"web/legacy/base.css", "web/legacy/old.js", "web/legacy/logo.svg"}
+)
+
+func newHybridExtractor() *testExtractor {
+	return &testExtractor{all: []*assetmin.SSRAssets{
+		{ModuleName: "example.com/app", CSS: ".app{color:red}", IsRoot: true},
+		{ModuleName: "github.com/acme/ui", CSS: ".ui{color:red}"},
+	}}
+}
+
+func TestPlainAssetDirs_ReadAsPlainFilesInSSRMode(t *testing.T) {
+	ex := newHybridExtractor()
+	env := newTestEnv(t, &assetmin.Config{PlainAssetDirs: []string{"web/legacy"}}).
+		writeFiles(legacyFiles).
+		loadSSR(ex).
+		ssrMode().
+		fileEvent("create", legacyPaths...)
+	am := env.AssetsHandler
+
+	if len(ex.extracted) != 0 {
+		t.Errorf("plain files re-extracted as modules: %v", ex.extracted)
+	}
+	if !am.ContainsJS("legacy") || !am.HasIcon("logo.svg") {
+		t.Error("plain script or icon not served")
+	}
+	// Plain files first, then the modules, then the root project.
+	css := outputString(t, am, "style.css")
+	legacy, ui, app := strings.Index(css, ".legacy"), strings.Index(css, ".ui"), strings.Index(css, ".app")
+	if legacy < 0 || ui < 0 || app < 0 || !(legacy < ui && ui < app) {
+		t.Errorf("style.css merge order wrong:\n%s", css)
+	}
+}
+
+func TestPlainAssetDirs_ModuleDirsStillReextract(t *testing.T) {
+	ex := newHybridExtractor()
+	env := newTestEnv(t, &assetmin.Config{PlainAssetDirs: []string{"web/legacy"}}).
+		writeFiles(legacyFiles).
+		loadSSR(ex).
+		ssrMode().
+		fileEvent("create", legacyPaths...)
+	am := env.AssetsHandler
+
+	button := filepath.Join(env.BaseDir, "components", "button")
+	if err := am.NewFileEvent("style.css", ".css", filepath.Join(button, "style.css"), "write"); err != nil {
+		t.Fatal(err)
+	}
+	if len(ex.extracted) != 1 || ex.extracted[0] != button {
+		t.Errorf("ExtractModule calls = %v, want [%s]", ex.extracted, button)
+	}
+}
+
+func TestPlainAssetDirs_DirectoryRemove(t *testing.T) {
+	env := newTestEnv(t, &assetmin.Config{PlainAssetDirs: []string{"web/legacy"}}).
+		writeFiles(legacyFiles).
+		loadSSR(newHybridExtractor()).
+		ssrMode().
+		fileEvent("create", legacyPaths...)
+	am, legacy := env.AssetsHandler, filepath.Join(env.BaseDir, "web", "legacy")
+
+	if err := os.RemoveAll(legacy); err != nil {
+		t.Fatal(err)
+	}
+	if err := am.NewFileEvent("legacy", "", legacy, "remove"); err != nil {
+		t.Fatal(err)
+	}
+	if am.ContainsCSS(".legacy") || am.ContainsJS("legacy") || am.HasIcon("logo.svg") {
+		t.Error("plain files of the removed directory still served")
+	}
+	if !am.ContainsCSS(".ui") || !am.ContainsCSS(".app") {
+		t.Error("module CSS dropped with the plain directory")
+	}
+}
+
+func TestPlainAssetDirs_ParentDirectoryRemove(t *testing.T) {
+	env := newTestEnv(t, &assetmin.Config{PlainAssetDirs: []string{"web/legacy"}}).
+		writeFiles(legacyFiles).
+		loadSSR(newHybridExtractor()).
+		ssrMode().
+		fileEvent("create", legacyPaths...)
+	am, legacy := env.AssetsHandler, filepath.Join(env.BaseDir, "web", "legacy")
+
+	web := filepath.Dir(legacy)
+	if err := os.RemoveAll(web); err != nil {
+		t.Fatal(err)
+	}
+	if err := am.NewFileEvent("web", "", web, "remove"); err != nil {
+		t.Fatal(err)
+	}
+	if am.ContainsCSS(".legacy") || am.ContainsJS("legacy") || am.HasIcon("logo.svg") {
+		t.Error("plain files below the removed parent directory still served")
+	}
+	if !am.ContainsCSS(".ui") || !am.ContainsCSS(".app") {
+		t.Error("module CSS dropped with the parent directory")
+	}
+}
+
+func TestPlainAssetDirs_GoFileStillCompiles(t *testing.T) {
+	env := newTestEnv(t, &assetmin.Config{PlainAssetDirs: []string{"web/legacy"}}).
+		writeFiles(legacyFiles).
+		writeFiles(map[string]string{"web/legacy/legacy.go": "package legacy", "web/legacy/notes.txt": "todo"}).
+		loadSSR(newHybridExtractor()).
+		ssrMode()
+	am := env.AssetsHandler
+	compiles := 0
+	am.SetSSRCompiler(func() error {
+		compiles++
+		return nil
+	})
+
+	env.fileEvent("write", "web/legacy/legacy.go", "web/legacy/notes.txt")
+	if compiles != 1 {
+		t.Errorf("onSSRCompile called %d times, want 1", compiles)
+	}
+}
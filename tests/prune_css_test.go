@@ -0,0 +1,104 @@
+//go:build !wasm
+
+package assetmin_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tinywasm/assetmin"
+	"github.com/tinywasm/js"
+)
+
+// pruneModules are a library stylesheet and an app whose markup and script
+// use part of it; pruneSafelist keeps two more of its rules.
+var (
+	pruneModules = []*assetmin.SSRAssets{
+		{ModuleName: "example.com/ui", CSS: ".btn{color:red}.card{margin:0}.keep-me{x:1}#modal{y:1}table{z:1}.toggled{w:1}"},
+		{
+			ModuleName: "example.com/app",
+			CSS:        ".app{p:0}",
+			JS:         []*js.Script{{Content: `el.classList.add("toggled")`}},
+			HTML:       `<main class="app"><button class="btn">Go</button></main>`,
+		},
+	}
+	pruneSafelist = []string{".keep-*", "#modal"}
+)
+
+func TestPruneCSS_DropsUnusedRules(t *testing.T) {
+	am := newTestEnv(t, &assetmin.Config{PruneCSS: true, CSSSafelist: pruneSafelist}).withModules(pruneModules...).AssetsHandler
+
+	css, err := am.GetMinifiedCSS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(css)
+
+	for _, want := range []string{".btn", ".app", ".keep-me", "#modal", ".toggled"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("pruned CSS must keep %s (HTML, JS or safelist): %s", want, got)
+		}
+	}
+	for _, unwanted := range []string{".card", "table"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("pruned CSS must drop %s: %s", unwanted, got)
+		}
+	}
+}
+
+func TestPruneCSS_ReportsPerModuleSavings(t *testing.T) {
+	am := newTestEnv(t, &assetmin.Config{PruneCSS: true, CSSSafelist: pruneSafelist}).withModules(pruneModules...).AssetsHandler
+
+	report := am.CSSPruneReport()
+	byModule := make(map[string]assetmin.CSSSavings)
+	for _, s := range report {
+		byModule[s.Module] = s
+	}
+
+	ui, ok := byModule["example.com/ui"]
+	if !ok {
+		t.Fatalf("report missing example.com/ui: %+v", report)
+	}
+	if ui.After >= ui.Before {
+		t.Errorf("example.com/ui should shrink: %+v", ui)
+	}
+	if app := byModule["example.com/app"]; app.After != app.Before {
+		t.Errorf("example.com/app uses all of its CSS, no savings expected: %+v", app)
+	}
+}
+
+func TestPruneCSS_DisabledInDevMode(t *testing.T) {
+	am := newTestEnv(t, &assetmin.Config{PruneCSS: true, CSSSafelist: pruneSafelist, DevMode: true}).withModules(pruneModules...).AssetsHandler
+
+	css, err := am.GetMinifiedCSS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(css), ".card") {
+		t.Errorf("DevMode must serve the full stylesheet: %s", css)
+	}
+	if report := am.CSSPruneReport(); report != nil {
+		t.Errorf("no report expected in DevMode, got %+v", report)
+	}
+}
+
+func TestPruneCSS_NewHTMLBringsRuleBack(t *testing.T) {
+	am := newTestEnv(t, &assetmin.Config{PruneCSS: true, CSSSafelist: pruneSafelist}).withModules(pruneModules...).AssetsHandler
+
+	if css, _ := am.GetMinifiedCSS(); strings.Contains(string(css), ".card") {
+		t.Fatalf(".card must be pruned before any markup uses it: %s", css)
+	}
+
+	am.InjectHTML(`<div class="card"></div>`)
+	if err := am.RegenerateHTMLCache(); err != nil {
+		t.Fatal(err)
+	}
+
+	css, err := am.GetMinifiedCSS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(css), ".card") {
+		t.Errorf(".card must come back once the HTML uses it: %s", css)
+	}
+}
@@ -0,0 +1,161 @@
+//go:build !wasm
+
+package assetmin_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tinywasm/assetmin"
+	"github.com/tinywasm/router/mock"
+)
+
+func invoke(r *mock.Router, path string) *mock.Context {
+	ctx := &mock.Context{InMethod: "GET", InPath: path}
+	r.Invoke("GET", path, ctx)
+	return ctx
+}
+
+func TestReadiness_States(t *testing.T) {
+	am := assetmin.NewAssetMin(&assetmin.Config{OutputDir: t.TempDir(), HealthPath: "/healthz"})
+	r := newTestRouter(am)
+
+	if state, err := am.Readiness(); state != assetmin.LoadReady || err != nil {
+		t.Errorf("before any load: %v, %v", state, err)
+	}
+
+	gate := &testExtractor{release: make(chan struct{}), all: []*assetmin.SSRAssets{{ModuleName: "app", CSS: ".app{color:red}"}}}
+	am.SetSSRExtractor(gate)
+	// The probe covers the startup window before the load is scheduled too.
+	if state, _ := am.Readiness(); state != assetmin.LoadLoading {
+		t.Errorf("before the load is scheduled: %v", state)
+	}
+	if ctx := invoke(r, "/healthz"); ctx.Status != 503 {
+		t.Errorf("health before the load is scheduled: %d %q", ctx.Status, ctx.ResponseBody())
+	}
+	am.LoadSSRModules()
+	if state, _ := am.Readiness(); state != assetmin.LoadLoading {
+		t.Errorf("during the load: %v", state)
+	}
+	ctx := invoke(r, "/healthz")
+	if ctx.Status != 503 || ctx.GetHeader("Retry-After") == "" || !strings.Contains(string(ctx.ResponseBody()), `"loading"`) {
+		t.Errorf("health while loading: %d %q", ctx.Status, ctx.ResponseBody())
+	}
+
+	close(gate.release)
+	am.WaitForSSRLoad(5 * time.Second)
+	if state, err := am.Readiness(); state != assetmin.LoadReady || err != nil {
+		t.Errorf("after the load: %v, %v", state, err)
+	}
+	ctx = invoke(r, "/healthz")
+	if (ctx.Status != 0 && ctx.Status != 200) || !strings.Contains(string(ctx.ResponseBody()), `"ready"`) {
+		t.Errorf("health when ready: %d %q", ctx.Status, ctx.ResponseBody())
+	}
+	for _, route := range r.Routes() {
+		if route.Path == "/healthz" && !route.Public {
+			t.Error("health route is not public")
+		}
+	}
+}
+
+func TestReadiness_Failed(t *testing.T) {
+	am := assetmin.NewAssetMin(&assetmin.Config{OutputDir: t.TempDir(), HealthPath: "/healthz", SSRRetry: fastRetry})
+	r := newTestRouter(am)
+	am.SetSSRExtractor(&testExtractor{err: errors.New("broken module")})
+	am.LoadSSRModules()
+	am.WaitForSSRLoad(30 * time.Second)
+
+	state, err := am.Readiness()
+	if state != assetmin.LoadFailed || err == nil {
+		t.Fatalf("after a failed load: %v, %v", state, err)
+	}
+	ctx := invoke(r, "/healthz")
+	if ctx.Status != 503 || !strings.Contains(string(ctx.ResponseBody()), "broken module") {
+		t.Errorf("health after failure: %d %q", ctx.Status, ctx.ResponseBody())
+	}
+}
+
+func TestLoadGate_Reject(t *testing.T) {
+	am := assetmin.NewAssetMin(&assetmin.Config{OutputDir: t.TempDir(), LoadGate: assetmin.LoadGateReject})
+	r := newTestRouter(am)
+	gate := &testExtractor{release: make(chan struct{}), all: []*assetmin.SSRAssets{{ModuleName: "app", CSS: ".app{color:red}"}}}
+	am.SetSSRExtractor(gate)
+	am.LoadSSRModules()
+
+	ctx := invoke(r, "/style.css")
+	if ctx.Status != 503 || ctx.GetHeader("Retry-After") != "1" {
+		t.Errorf("during the load: status %d, Retry-After %q", ctx.Status, ctx.GetHeader("Retry-After"))
+	}
+
+	close(gate.release)
+	am.WaitForSSRLoad(5 * time.Second)
+	ctx = invoke(r, "/style.css")
+	if ctx.Status == 503 || !strings.Contains(string(ctx.ResponseBody()), ".app{") {
+		t.Errorf("after the load: status %d, body %q", ctx.Status, ctx.ResponseBody())
+	}
+}
+
+func TestLoadGate_Block(t *testing.T) {
+	am := assetmin.NewAssetMin(&assetmin.Config{OutputDir: t.TempDir(), LoadGate: assetmin.LoadGateBlock, LoadGateTimeout: 5 * time.Second})
+	r := newTestRouter(am)
+	gate := &testExtractor{release: make(chan struct{}), all: []*assetmin.SSRAssets{{ModuleName: "app", CSS: ".app{color:red}"}}}
+	am.SetSSRExtractor(gate)
+	am.LoadSSRModules()
+
+	served := make(chan *mock.Context)
+	go func() { served <- invoke(r, "/style.css") }()
+	select {
+	case <-served:
+		t.Fatal("request answered before the load completed")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(gate.release)
+	ctx := <-served
+	if ctx.Status == 503 || !strings.Contains(string(ctx.ResponseBody()), ".app{") {
+		t.Errorf("blocked request: status %d, body %q", ctx.Status, ctx.ResponseBody())
+	}
+}
+
+func TestLoadGate_BlockTimesOut(t *testing.T) {
+	am := assetmin.NewAssetMin(&assetmin.Config{OutputDir: t.TempDir(), LoadGate: assetmin.LoadGateBlock, LoadGateTimeout: 20 * time.Millisecond})
+	r := newTestRouter(am)
+	gate := &testExtractor{release: make(chan struct{})}
+	defer close(gate.release)
+	am.SetSSRExtractor(gate)
+	am.LoadSSRModules()
+
+	if ctx := invoke(r, "/style.css"); ctx.Status != 503 {
+		t.Errorf("status %d after the timeout, want 503", ctx.Status)
+	}
+}
+
+func TestLoadGate_LaterLoadsDoNotGate(t *testing.T) {
+	am := assetmin.NewAssetMin(&assetmin.Config{OutputDir: t.TempDir(), LoadGate: assetmin.LoadGateReject, HealthPath: "/healthz"})
+	r := newTestRouter(am)
+	am.SetSSRExtractor(&testExtractor{all: []*assetmin.SSRAssets{{ModuleName: "app", CSS: ".app{color:red}"}}})
+	am.LoadSSRModules()
+	am.WaitForSSRLoad(5 * time.Second)
+
+	gate := &testExtractor{release: make(chan struct{}), all: []*assetmin.SSRAssets{{ModuleName: "app", CSS: ".app{color:blue}"}}}
+	am.SetSSRExtractor(gate)
+	am.LoadSSRModules()
+
+	if state, err := am.Readiness(); state != assetmin.LoadReady || err != nil {
+		t.Errorf("during a second load: %v, %v", state, err)
+	}
+	if ctx := invoke(r, "/healthz"); ctx.Status != 0 && ctx.Status != 200 {
+		t.Errorf("health during a second load: %d %q", ctx.Status, ctx.ResponseBody())
+	}
+	ctx := invoke(r, "/style.css")
+	if ctx.Status == 503 || !strings.Contains(string(ctx.ResponseBody()), ".app{color:red}") {
+		t.Errorf("a second load must not gate: status %d, body %q", ctx.Status, ctx.ResponseBody())
+	}
+
+	close(gate.release)
+	am.WaitForSSRLoad(5 * time.Second)
+	if ctx := invoke(r, "/style.css"); !strings.Contains(string(ctx.ResponseBody()), ".app{color:blue}") {
+		t.Errorf("after the second load: %q", ctx.ResponseBody())
+	}
+}
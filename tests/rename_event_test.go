@@ -0,0 +1,132 @@
+//go:build !wasm
+
+package assetmin_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tinywasm/assetmin"
+)
+
+func TestRenameEvent_IdenticalContentMovesOnlyThatEntry(t *testing.T) {
+	env := newTestEnv(t, &assetmin.Config{}).
+		writeFiles(map[string]string{"a.js": "console.log('same')", "b.js": "console.log('same')"}).
+		fileEvent("create", "a.js", "b.js").
+		writeFiles(map[string]string{"c.js": "console.log('same')"})
+	am, root := env.AssetsHandler, env.BaseDir
+
+	if err := am.RenameFile(filepath.Join(root, "b.js"), filepath.Join(root, "c.js")); err != nil {
+		t.Fatalf("RenameFile: %v", err)
+	}
+
+	js := outputString(t, am, "script.js")
+	if got := strings.Count(js, "same"); got != 2 {
+		t.Errorf("script.js has %d copies of the shared content, want 2:\n%s", got, js)
+	}
+}
+
+func TestRenameEvent_EditedWhileRenamed(t *testing.T) {
+	env := newTestEnv(t, &assetmin.Config{}).
+		writeFiles(map[string]string{"a.css": ".a{color:red}", "b.css": ".b{color:red}"}).
+		fileEvent("create", "a.css", "b.css").
+		writeFiles(map[string]string{"z.css": ".z{color:blue}"})
+	am, root := env.AssetsHandler, env.BaseDir
+
+	if err := am.NewFileRenameEvent(filepath.Join(root, "a.css"), filepath.Join(root, "z.css")); err != nil {
+		t.Fatalf("NewFileRenameEvent: %v", err)
+	}
+
+	css := outputString(t, am, "style.css")
+	if strings.Contains(css, ".a{") {
+		t.Error("stale entry of the old path left in style.css")
+	}
+	// Path-sorted order: b.css before z.css.
+	if b, z := strings.Index(css, ".b{"), strings.Index(css, ".z{"); b < 0 || z < 0 || b > z {
+		t.Errorf("style.css order wrong:\n%s", css)
+	}
+}
+
+func TestRenameEvent_AcrossExtensions(t *testing.T) {
+	env := newTestEnv(t, &assetmin.Config{}).
+		writeFiles(map[string]string{"moved.css": ".moved{color:red}"}).
+		fileEvent("create", "moved.css").
+		writeFiles(map[string]string{"moved.js": "console.log('moved')"})
+	am, root := env.AssetsHandler, env.BaseDir
+
+	if err := am.RenameFile(filepath.Join(root, "moved.css"), filepath.Join(root, "moved.js")); err != nil {
+		t.Fatalf("RenameFile: %v", err)
+	}
+	if am.ContainsCSS(".moved") {
+		t.Error("style.css still holds the renamed file")
+	}
+	if !am.ContainsJS("moved") {
+		t.Error("script.js missing the renamed file")
+	}
+}
+
+func TestRenameEvent_Icon(t *testing.T) {
+	icon := `<svg viewBox="0 0 24 24"><path d="M0 0h24v24H0z"/></svg>`
+	env := newTestEnv(t, &assetmin.Config{}).
+		writeFiles(map[string]string{"home.svg": icon}).
+		fileEvent("create", "home.svg").
+		writeFiles(map[string]string{"house.svg": icon})
+	am, root := env.AssetsHandler, env.BaseDir
+
+	if err := am.RenameFile(filepath.Join(root, "home.svg"), filepath.Join(root, "house.svg")); err != nil {
+		t.Fatalf("RenameFile: %v", err)
+	}
+	if am.HasIcon("home.svg") || !am.HasIcon("house.svg") {
+		t.Errorf("sprite after rename: %s", outputString(t, am, "icons.svg"))
+	}
+}
+
+func TestRenameEvent_IconMovedToEarlierDirectory(t *testing.T) {
+	icon := `<svg viewBox="0 0 24 24"><path d="M0 0h24v24H0z"/></svg>`
+	for _, debounce := range []time.Duration{0, time.Hour} {
+		env := newTestEnv(t, &assetmin.Config{EventDebounce: debounce}).
+			writeFiles(map[string]string{"icons/b/home.svg": icon}).
+			fileEvent("create", "icons/b/home.svg")
+		am, root := env.AssetsHandler, env.BaseDir
+		if err := am.FlushEvents(); err != nil {
+			t.Fatal(err)
+		}
+		oldPath, newPath := filepath.Join(root, "icons", "b", "home.svg"), filepath.Join(root, "icons", "a", "home.svg")
+		os.MkdirAll(filepath.Dir(newPath), 0755)
+		if err := os.Rename(oldPath, newPath); err != nil {
+			t.Fatal(err)
+		}
+
+		// The create of icons/a sorts before the removal of icons/b.
+		if err := am.NewFileRenameEvent(oldPath, newPath); err != nil {
+			t.Fatalf("NewFileRenameEvent: %v", err)
+		}
+		if err := am.FlushEvents(); err != nil {
+			t.Fatalf("FlushEvents: %v", err)
+		}
+		if !am.HasIcon("home.svg") {
+			t.Errorf("debounce %v: icon lost by the move: %s", debounce, outputString(t, am, "icons.svg"))
+		}
+	}
+}
+
+func TestRenameEvent_QueuedSupersedesEarlierEvents(t *testing.T) {
+	env := newTestEnv(t, &assetmin.Config{EventDebounce: time.Hour}).
+		writeFiles(map[string]string{"old.css": ".old{color:red}"}).
+		fileEvent("create", "old.css").
+		writeFiles(map[string]string{"new.css": ".new{color:red}"})
+	am := env.AssetsHandler
+	oldPath, newPath := filepath.Join(env.BaseDir, "old.css"), filepath.Join(env.BaseDir, "new.css")
+	if err := am.NewFileRenameEvent(oldPath, newPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := am.FlushEvents(); err != nil {
+		t.Fatalf("FlushEvents: %v", err)
+	}
+	if am.ContainsCSS(".old") || !am.ContainsCSS(".new") {
+		t.Errorf("style.css after queued rename: %s", outputString(t, am, "style.css"))
+	}
+}
@@ -0,0 +1,147 @@
+//go:build !wasm
+
+package assetmin_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tinywasm/assetmin"
+)
+
+var fastRetry = assetmin.RetryPolicy{Attempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond}
+
+func TestRetryPolicy_TransientFailuresExhaustAttempts(t *testing.T) {
+	am := assetmin.NewAssetMin(&assetmin.Config{OutputDir: t.TempDir(), SSRRetry: fastRetry})
+	ex := &retryExtractor{failCount: 10, failResult: errors.New("go list: signal killed")}
+	am.SetSSRExtractor(ex)
+	am.LoadSSRModules()
+	waitForSSRLoad(t, am, time.Second, "load did not finish")
+
+	if ex.calls != 3 {
+		t.Errorf("ExtractAll called %d times, want 3", ex.calls)
+	}
+	var loadErr *assetmin.LoadError
+	if !errors.As(am.SSRLoadError(), &loadErr) {
+		t.Fatalf("SSRLoadError() = %v, want a *LoadError", am.SSRLoadError())
+	}
+	if loadErr.Kind != assetmin.FailureTransient || len(loadErr.Attempts) != 3 || !errors.Is(loadErr, ex.failResult) {
+		t.Errorf("LoadError = %+v", loadErr)
+	}
+	attempts := am.SSRLoadAttempts()
+	if len(attempts) != 3 {
+		t.Fatalf("SSRLoadAttempts() = %d entries, want 3", len(attempts))
+	}
+	for _, a := range attempts {
+		if a.Err == nil || a.Kind != assetmin.FailureTransient || a.Start.IsZero() {
+			t.Errorf("attempt %+v", a)
+		}
+	}
+}
+
+func TestRetryPolicy_ModuleErrorIsNotRetried(t *testing.T) {
+	am := assetmin.NewAssetMin(&assetmin.Config{OutputDir: t.TempDir(), SSRRetry: fastRetry})
+	compileErr := errors.New("css.go:12: undefined: Stylesheet")
+	ex := &retryExtractor{failCount: 10, failResult: &assetmin.ModuleError{Module: "github.com/acme/ui", Err: compileErr}}
+	am.SetSSRExtractor(ex)
+	am.LoadSSRModules()
+	am.WaitForSSRLoad(time.Second)
+
+	if ex.calls != 1 {
+		t.Errorf("ExtractAll called %d times, want 1", ex.calls)
+	}
+	var loadErr *assetmin.LoadError
+	if !errors.As(am.SSRLoadError(), &loadErr) {
+		t.Fatalf("SSRLoadError() = %v", am.SSRLoadError())
+	}
+	if loadErr.Kind != assetmin.FailureModule || loadErr.Module != "github.com/acme/ui" || !errors.Is(loadErr, compileErr) {
+		t.Errorf("LoadError = %+v", loadErr)
+	}
+	if !strings.Contains(loadErr.Error(), "github.com/acme/ui") {
+		t.Errorf("error does not name the module: %v", loadErr)
+	}
+	if state, _ := am.Readiness(); state != assetmin.LoadFailed {
+		t.Errorf("Readiness = %v", state)
+	}
+}
+
+func TestRetryPolicy_GoBuildFailureIsAModuleError(t *testing.T) {
+	ex := &testExtractor{all: []*assetmin.SSRAssets{{ModuleName: "github.com/acme/ui", CSS: ".ui{color:red}"}}}
+	am := newTestEnv(t, &assetmin.Config{SSRRetry: fastRetry}).loadSSR(ex).AssetsHandler
+
+	// A package of the loaded module stops compiling.
+	buildErr := errors.New("go run: exit status 1\n# github.com/acme/ui/internal/theme\nui/internal/theme/css.go:12:2: undefined: Stylesheet")
+	ex.all, ex.err = nil, buildErr
+	am.LoadSSRModules()
+	am.WaitForSSRLoad(time.Second)
+
+	if n := len(am.SSRLoadAttempts()); n != 1 {
+		t.Errorf("ExtractAll called %d times, want 1", n)
+	}
+	var loadErr *assetmin.LoadError
+	if !errors.As(am.SSRLoadError(), &loadErr) {
+		t.Fatalf("SSRLoadError() = %v", am.SSRLoadError())
+	}
+	if loadErr.Kind != assetmin.FailureModule || loadErr.Module != "github.com/acme/ui" || !errors.Is(loadErr, buildErr) {
+		t.Errorf("LoadError = %+v", loadErr)
+	}
+	if mods := am.Modules(); len(mods) != 1 || mods[0].Name != "github.com/acme/ui" || mods[0].Error == "" {
+		t.Errorf("failure not reported against the module: %+v", mods)
+	}
+
+	// Packages of no loaded module, the generated main among them, stay
+	// transient and get no entry.
+	for _, header := range []string{"# runtime/cgo", "# command-line-arguments"} {
+		ex.err = errors.New(header + "\ngcc: command not found")
+		am.LoadSSRModules()
+		am.WaitForSSRLoad(time.Second)
+		if n := len(am.SSRLoadAttempts()); n != 3 {
+			t.Errorf("%s: ExtractAll called %d times, want 3", header, n)
+		}
+		if mods := am.Modules(); len(mods) != 1 {
+			t.Errorf("%s: Modules() = %+v, want only the loaded module", header, mods)
+		}
+	}
+}
+
+func TestRetryPolicy_GoBuildFailureOnTheFirstLoad(t *testing.T) {
+	// No module is registered yet: the package is matched against the go.mod
+	// of RootDir, or taken as a dependency by its domain.
+	for _, pkg := range []string{"app/ui", "github.com/acme/ui"} {
+		env := newTestEnv(t, &assetmin.Config{SSRRetry: fastRetry}).writeFiles(map[string]string{"go.mod": "module app\n\ngo 1.22\n"})
+		am := env.AssetsHandler
+		buildErr := errors.New("go run: exit status 1\n# " + pkg + "\ncss.go:12:2: undefined: Stylesheet")
+		am.SetSSRExtractor(&testExtractor{err: buildErr})
+		am.LoadSSRModules()
+		waitForSSRLoad(t, am, time.Second, "load did not finish")
+
+		if n := len(am.SSRLoadAttempts()); n != 1 {
+			t.Errorf("%s: ExtractAll called %d times, want 1", pkg, n)
+		}
+		var loadErr *assetmin.LoadError
+		if !errors.As(am.SSRLoadError(), &loadErr) || loadErr.Kind != assetmin.FailureModule || loadErr.Module != pkg {
+			t.Errorf("%s: SSRLoadError() = %+v", pkg, am.SSRLoadError())
+		}
+		if mods := am.Modules(); len(mods) != 1 || mods[0].Name != pkg || mods[0].Error == "" {
+			t.Errorf("%s: failure not reported against the package: %+v", pkg, mods)
+		}
+	}
+}
+
+func TestRetryPolicy_HistoryOfARecoveredLoad(t *testing.T) {
+	am := assetmin.NewAssetMin(&assetmin.Config{OutputDir: t.TempDir(), SSRRetry: fastRetry})
+	ex := &retryExtractor{failCount: 2, failResult: errors.New("flaky"), successRes: []*assetmin.SSRAssets{{ModuleName: "m", CSS: ".m{color:red}"}}}
+	am.SetSSRExtractor(ex)
+	am.LoadSSRModules()
+	am.WaitForSSRLoad(time.Second)
+
+	if err := am.SSRLoadError(); err != nil {
+		t.Fatalf("SSRLoadError() = %v", err)
+	}
+	attempts := am.SSRLoadAttempts()
+	if len(attempts) != 3 || attempts[0].Err == nil || attempts[2].Err != nil {
+		t.Errorf("SSRLoadAttempts() = %+v", attempts)
+	}
+}
@@ -3,10 +3,13 @@
 package assetmin_test
 
 import (
+	"context"
 	"github.com/tinywasm/assetmin"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 )
 
 // TestEnvironment holds all the paths and components needed for asset tests
@@ -111,11 +114,149 @@ func (env *TestEnvironment) CreateModulesDir() *TestEnvironment {
 	return env
 }
 
-// withModules inyecta directorios de módulos sin ejecutar go list
-func (env *TestEnvironment) withModules(dirs ...string) *TestEnvironment {
+// newTestEnv builds an environment around cfg. The BaseDir is cfg.RootDir
+// and the PublicDir cfg.OutputDir; unset, each gets a fresh directory.
+func newTestEnv(t *testing.T, cfg *assetmin.Config) *TestEnvironment {
+	t.Helper()
+	if cfg.RootDir == "" {
+		cfg.RootDir = t.TempDir()
+	}
+	baseDir := cfg.RootDir
+	if cfg.OutputDir == "" {
+		cfg.OutputDir = t.TempDir()
+	}
+	assetsHandler := assetmin.NewAssetMin(cfg)
+
+	return &TestEnvironment{
+		BaseDir:       baseDir,
+		ThemeDir:      filepath.Join(baseDir, "web", "theme"),
+		PublicDir:     cfg.OutputDir,
+		ModulesDir:    filepath.Join(baseDir, "modules"),
+		MainJsPath:    assetsHandler.GetMainJsPath(),
+		MainCssPath:   assetsHandler.GetMainCssPath(),
+		MainSvgPath:   assetsHandler.GetMainSvgPath(),
+		MainHtmlPath:  assetsHandler.GetMainHtmlPath(),
+		AssetsHandler: assetsHandler,
+		t:             t,
+		OutDir:        cfg.OutputDir,
+	}
+}
+
+// withModules inyecta módulos con UpdateSSRModule, sin ejecutar go list
+func (env *TestEnvironment) withModules(mods ...*assetmin.SSRAssets) *TestEnvironment {
+	env.t.Helper()
+	for _, m := range mods {
+		if err := env.AssetsHandler.UpdateSSRModule(m.ModuleName, m.CSS, m.JS, m.HTML, m.Icons); err != nil {
+			env.t.Fatalf("UpdateSSRModule(%s): %v", m.ModuleName, err)
+		}
+	}
 	return env
 }
 
+// loadSSR sets ex as the SSR extractor and waits for the full load.
+func (env *TestEnvironment) loadSSR(ex assetmin.SSRExtractor) *TestEnvironment {
+	env.t.Helper()
+	env.AssetsHandler.SetSSRExtractor(ex)
+	env.AssetsHandler.LoadSSRModules()
+	waitForSSRLoad(env.t, env.AssetsHandler, 5*time.Second, "load did not finish")
+	return env
+}
+
+// waitForSSRLoad fails the test with msg unless the SSR load (or the queued
+// events) finish within timeout.
+func waitForSSRLoad(t *testing.T, am *assetmin.AssetMin, timeout time.Duration, msg string) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := am.WaitForSSRLoadContext(ctx); err != nil {
+		t.Fatalf("%s: %v", msg, err)
+	}
+}
+
+// ssrMode switches the handler to SSR mode.
+func (env *TestEnvironment) ssrMode() *TestEnvironment {
+	env.AssetsHandler.EnableSSRMode()
+	return env
+}
+
+// writeFiles writes each file, by path relative to the BaseDir, without
+// sending any event.
+func (env *TestEnvironment) writeFiles(files map[string]string) *TestEnvironment {
+	env.t.Helper()
+	for name, content := range files {
+		path := filepath.Join(env.BaseDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			env.t.Fatal(err)
+		}
+		writeAsset(env.t, path, content)
+	}
+	return env
+}
+
+// fileEvent sends event for each path relative to the BaseDir, in order;
+// a path without extension is a directory.
+func (env *TestEnvironment) fileEvent(event string, names ...string) *TestEnvironment {
+	env.t.Helper()
+	for _, name := range names {
+		path := filepath.Join(env.BaseDir, name)
+		if err := env.AssetsHandler.NewFileEvent(filepath.Base(name), filepath.Ext(name), path, event); err != nil {
+			env.t.Fatalf("NewFileEvent(%s, %s): %v", name, event, err)
+		}
+	}
+	return env
+}
+
+// countingSink counts the writes of each output.
+type countingSink struct {
+	assetmin.OutputSink
+	mu     sync.Mutex
+	writes map[string]int
+}
+
+func newCountingSink() *countingSink {
+	return &countingSink{OutputSink: assetmin.NewMemorySink(), writes: make(map[string]int)}
+}
+
+func (s *countingSink) WriteFile(name string, content []byte) error {
+	s.mu.Lock()
+	s.writes[name]++
+	s.mu.Unlock()
+	return s.OutputSink.WriteFile(name, content)
+}
+
+func (s *countingSink) count(name string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.writes[name]
+}
+
+// testExtractor is the configurable SSRExtractor of the tests. ExtractAll
+// serves all, or fails with err, once release (when set) is closed.
+// ExtractModule serves byDir and errs and records every directory asked for.
+type testExtractor struct {
+	all       []*assetmin.SSRAssets
+	err       error
+	release   chan struct{}
+	byDir     map[string]*assetmin.SSRAssets
+	errs      map[string]error
+	extracted []string
+}
+
+func (e *testExtractor) ExtractModule(moduleDir string) (*assetmin.SSRAssets, error) {
+	e.extracted = append(e.extracted, moduleDir)
+	if err := e.errs[moduleDir]; err != nil {
+		return nil, err
+	}
+	return e.byDir[moduleDir], nil
+}
+
+func (e *testExtractor) ExtractAll() ([]*assetmin.SSRAssets, error) {
+	if e.release != nil {
+		<-e.release
+	}
+	return e.all, e.err
+}
+
 // writeModuleSSR crea moduleDir/ssr.go con RenderCSS y/o RenderJS mínimos
 func (env *TestEnvironment) writeModuleSSR(name, css, js string) string {
 	moduleDir := filepath.Join(env.BaseDir, "modules", name)
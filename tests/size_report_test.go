@@ -0,0 +1,156 @@
+//go:build !wasm
+
+package assetmin_test
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tinywasm/assetmin"
+	"github.com/tinywasm/font"
+	"github.com/tinywasm/svg/sprite"
+)
+
+// sizeModules are a module with two rules and an icon and a smaller one.
+func sizeModules() []*assetmin.SSRAssets {
+	icons := sprite.NewSprite()
+	icons.AddRaw("icon-big", `<path d="M0 0h24v24H0z"/>`, "0 0 24 24")
+	return []*assetmin.SSRAssets{
+		{ModuleName: "example.com/big", CSS: ".big{color:red}\n.big-2 { margin : 0 }", Icons: icons},
+		{ModuleName: "example.com/small", CSS: ".s{x:1}"},
+	}
+}
+
+func TestSizeReport_PerContentFile(t *testing.T) {
+	am := newTestEnv(t, &assetmin.Config{}).withModules(sizeModules()...).AssetsHandler
+
+	report, err := am.SizeReport()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var css *assetmin.AssetSize
+	for i := range report.Assets {
+		if report.Assets[i].Name == "style.css" {
+			css = &report.Assets[i]
+		}
+	}
+	if css == nil {
+		t.Fatalf("report missing style.css: %+v", report.Assets)
+	}
+
+	var big *assetmin.ContentSize
+	for i := range css.Contents {
+		if css.Contents[i].Path == "example.com/big" {
+			big = &css.Contents[i]
+		}
+	}
+	if big == nil {
+		t.Fatalf("style.css contents missing example.com/big: %+v", css.Contents)
+	}
+	if big.Slot != "middle" {
+		t.Errorf("slot = %q, want middle", big.Slot)
+	}
+	if big.Raw != len(".big{color:red}\n.big-2 { margin : 0 }") {
+		t.Errorf("raw = %d", big.Raw)
+	}
+	if big.Minified == 0 || big.Minified >= big.Raw {
+		t.Errorf("minified = %d, want 0 < minified < raw (%d)", big.Minified, big.Raw)
+	}
+
+	if len(report.Icons) != 1 || report.Icons[0].ID != "icon-big" || report.Icons[0].Module != "example.com/big" {
+		t.Errorf("icons = %+v", report.Icons)
+	}
+}
+
+func TestSizeBudgets_FailFlushToDisk(t *testing.T) {
+	am := newTestEnv(t, &assetmin.Config{
+		SizeBudgets: []assetmin.SizeBudget{
+			{Asset: "style.css", Module: "example.com/big", MaxBytes: 10},
+			{Module: "example.com/small", MaxBytes: 1000},
+		},
+	}).withModules(sizeModules()...).AssetsHandler
+
+	err := am.FlushToDisk()
+	var budgetErr *assetmin.BudgetError
+	if !errors.As(err, &budgetErr) {
+		t.Fatalf("expected *BudgetError, got %v", err)
+	}
+	if len(budgetErr.Violations) != 1 || budgetErr.Violations[0].Budget.Module != "example.com/big" {
+		t.Errorf("violations = %+v", budgetErr.Violations)
+	}
+	if _, statErr := os.Stat(am.GetMainCssPath()); statErr == nil {
+		t.Error("nothing must be written when a budget is exceeded")
+	}
+}
+
+func TestSizeBudgets_LoggedInDevMode(t *testing.T) {
+	am := newTestEnv(t, &assetmin.Config{
+		DevMode:     true,
+		SizeBudgets: []assetmin.SizeBudget{{Asset: "style.css", MaxBytes: 1}},
+	}).withModules(sizeModules()...).AssetsHandler
+	var logs []string
+	am.SetLog(func(message ...any) {
+		for _, m := range message {
+			if s, ok := m.(string); ok {
+				logs = append(logs, s)
+			}
+		}
+	})
+
+	if err := am.FlushToDisk(); err != nil {
+		t.Fatalf("DevMode must not fail on budgets: %v", err)
+	}
+	if !strings.Contains(strings.Join(logs, " "), "BUDGET EXCEEDED") {
+		t.Errorf("DevMode must log the exceeded budget, logs: %v", logs)
+	}
+}
+
+func TestSizeReport_WrittenByFlushToDisk(t *testing.T) {
+	am := newTestEnv(t, &assetmin.Config{WriteSizeReport: true}).withModules(sizeModules()...).AssetsHandler
+
+	if err := am.FlushToDisk(); err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := os.ReadFile(filepath.Join(am.OutputDir, "asset-sizes.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report assetmin.SizeReport
+	if err := json.Unmarshal(raw, &report); err != nil {
+		t.Fatalf("invalid report: %v\n%s", err, raw)
+	}
+	if len(report.Assets) == 0 {
+		t.Errorf("report lists no assets:\n%s", raw)
+	}
+}
+
+func TestSizeReport_SkipsAbsentFontFaces(t *testing.T) {
+	root := t.TempDir()
+	writeFaceFiles(t, filepath.Join(root, "fonts"), "Roboto", nil)
+	am := newTestEnv(t, &assetmin.Config{RootDir: root}).
+		loadSSR(&fontsExtractor{assets: &assetmin.SSRAssets{ModuleName: "app", IsRoot: true, Fonts: font.Declare("Roboto", "fonts")}}).
+		AssetsHandler
+
+	bold := font.Family("Roboto").Face(font.Bold) + ".ttf"
+	if err := os.Remove(filepath.Join(root, "fonts", bold)); err != nil {
+		t.Fatal(err)
+	}
+	report, err := am.SizeReport()
+	if err != nil {
+		t.Fatalf("an absent face failed the report: %v", err)
+	}
+	if len(report.Fonts) != 3 {
+		t.Errorf("fonts = %+v, want the 3 faces left", report.Fonts)
+	}
+	for _, f := range report.Fonts {
+		if f.Name == bold {
+			t.Errorf("absent face %s reported", bold)
+		}
+	}
+}
@@ -0,0 +1,300 @@
+//go:build !wasm
+
+package assetmin_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/tinywasm/assetmin"
+	"github.com/tinywasm/js"
+	"github.com/tinywasm/router/mock"
+)
+
+type sourceMap struct {
+	Version  int      `json:"version"`
+	File     string   `json:"file"`
+	Sources  []string `json:"sources"`
+	Mappings string   `json:"mappings"`
+}
+
+// sourceAt decodes the mappings and returns the source of the segment that
+// covers the given generated line and column ("" when it is unmapped).
+func (m sourceMap) sourceAt(t *testing.T, line, col int) string {
+	t.Helper()
+	source, _ := m.originAt(t, line, col)
+	return source
+}
+
+// originAt is sourceAt that also returns the original line.
+func (m sourceMap) originAt(t *testing.T, line, col int) (string, int) {
+	t.Helper()
+	const alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+	source, origLine := 0, 0
+	found, foundLine := "", 0
+	for l, group := range strings.Split(m.Mappings, ";") {
+		genCol := 0
+		for _, seg := range strings.Split(group, ",") {
+			if seg == "" {
+				continue
+			}
+			var fields []int
+			value, shift := 0, 0
+			for _, ch := range seg {
+				digit := strings.IndexRune(alphabet, ch)
+				value += (digit & 31) << shift
+				if digit&32 != 0 {
+					shift += 5
+					continue
+				}
+				if value&1 == 1 {
+					fields = append(fields, -(value >> 1))
+				} else {
+					fields = append(fields, value>>1)
+				}
+				value, shift = 0, 0
+			}
+			if len(fields) < 4 {
+				t.Fatalf("segment %q has %d fields", seg, len(fields))
+			}
+			genCol += fields[0]
+			source += fields[1]
+			origLine += fields[2]
+			if l == line && genCol <= col {
+				found, foundLine = m.Sources[source], origLine
+			}
+		}
+		if l == line {
+			break
+		}
+	}
+	return found, foundLine
+}
+
+// sourceMapModules each contribute a rule and a script to the bundles.
+var sourceMapModules = []*assetmin.SSRAssets{
+	{ModuleName: "example.com/alpha", CSS: ".alpha{color:red}", JS: []*js.Script{{Content: "console.log('alpha')"}}},
+	{ModuleName: "example.com/beta", CSS: ".beta{color:blue}", JS: []*js.Script{{Content: "console.log('beta')"}}},
+}
+
+func TestSourceMaps_MapBundleLinesToModules(t *testing.T) {
+	am := newTestEnv(t, &assetmin.Config{SourceMaps: true, DevMode: true}).withModules(sourceMapModules...).AssetsHandler
+
+	for _, tc := range []struct {
+		name   string
+		bundle func() ([]byte, error)
+		srcMap func() ([]byte, error)
+		marker map[string]string // module -> text on its generated line
+		ref    string
+	}{
+		{"css", am.GetMinifiedCSS, am.GetCSSSourceMap,
+			map[string]string{"example.com/alpha": ".alpha", "example.com/beta": ".beta"},
+			"/*# sourceMappingURL=style.css.map */"},
+		{"js", am.GetMinifiedJS, am.GetJSSourceMap,
+			map[string]string{"example.com/alpha": "alpha", "example.com/beta": "beta"},
+			"//# sourceMappingURL=script.js.map"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			bundle, err := tc.bundle()
+			if err != nil {
+				t.Fatal(err)
+			}
+			raw, err := tc.srcMap()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var m sourceMap
+			if err := json.Unmarshal(raw, &m); err != nil {
+				t.Fatalf("invalid source map %s: %v", raw, err)
+			}
+			if m.Version != 3 {
+				t.Errorf("version = %d, want 3", m.Version)
+			}
+			if !strings.Contains(string(bundle), tc.ref) {
+				t.Errorf("bundle must reference its map with %q:\n%s", tc.ref, bundle)
+			}
+
+			lines := strings.Split(string(bundle), "\n")
+			for module, marker := range tc.marker {
+				found := false
+				for i, line := range lines {
+					if col := strings.Index(line, marker); col >= 0 && !strings.Contains(line, "sourceMappingURL") {
+						found = true
+						if got := m.sourceAt(t, i, col); got != module {
+							t.Errorf("%q at %d:%d maps to %q, want %q", marker, i, col, got, module)
+						}
+					}
+				}
+				if !found {
+					t.Fatalf("marker %q not found in bundle:\n%s", marker, bundle)
+				}
+			}
+		})
+	}
+}
+
+func TestSourceMaps_ManyModulesMapToTheirOwn(t *testing.T) {
+	var modules []*assetmin.SSRAssets
+	for i := range 60 {
+		name := fmt.Sprintf("m%02d", i)
+		modules = append(modules, &assetmin.SSRAssets{
+			ModuleName: "example.com/" + name,
+			CSS:        "." + name + " { color: red; }",
+			JS:         []*js.Script{{Content: "console.log('" + name + "');"}},
+		})
+	}
+	am := newTestEnv(t, &assetmin.Config{SourceMaps: true, DevMode: true}).withModules(modules...).AssetsHandler
+
+	for _, tc := range []struct {
+		bundle, srcMap func() ([]byte, error)
+		marker         string // printf pattern of a module's text
+	}{
+		{am.GetMinifiedCSS, am.GetCSSSourceMap, ".m%02d{"},
+		{am.GetMinifiedJS, am.GetJSSourceMap, `"m%02d"`},
+	} {
+		bundle, err := tc.bundle()
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw, err := tc.srcMap()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var m sourceMap
+		if err := json.Unmarshal(raw, &m); err != nil {
+			t.Fatalf("invalid source map %s: %v", raw, err)
+		}
+		lines := strings.Split(string(bundle), "\n")
+		for i := range modules {
+			marker := fmt.Sprintf(tc.marker, i)
+			found := false
+			for l, line := range lines {
+				if col := strings.Index(line, marker); col >= 0 {
+					found = true
+					if got := m.sourceAt(t, l, col); got != modules[i].ModuleName {
+						t.Errorf("%q at %d:%d maps to %q", marker, l, col, got)
+					}
+				}
+			}
+			if !found {
+				t.Fatalf("marker %q not found in bundle:\n%s", marker, bundle)
+			}
+		}
+	}
+}
+
+func TestSourceMaps_ServedOnlyInDevMode(t *testing.T) {
+	routes := func(am *assetmin.AssetMin) map[string]bool {
+		r := &mock.Router{}
+		am.RegisterRoutes(r)
+		out := make(map[string]bool)
+		for _, route := range r.Routes() {
+			out[route.Path] = true
+		}
+		return out
+	}
+
+	dev := routes(newTestEnv(t, &assetmin.Config{SourceMaps: true, DevMode: true}).withModules(sourceMapModules...).AssetsHandler)
+	if !dev["/style.css.map"] || !dev["/script.js.map"] {
+		t.Errorf("DevMode must serve the source maps, routes: %v", dev)
+	}
+
+	prod := routes(newTestEnv(t, &assetmin.Config{SourceMaps: true}).withModules(sourceMapModules...).AssetsHandler)
+	if prod["/style.css.map"] || prod["/script.js.map"] {
+		t.Errorf("source maps must not be served outside DevMode, routes: %v", prod)
+	}
+
+	am := newTestEnv(t, &assetmin.Config{SourceMaps: true}).withModules(sourceMapModules...).AssetsHandler
+	css, err := am.GetMinifiedCSS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(css), "sourceMappingURL") {
+		t.Errorf("an unreachable map must not be referenced:\n%s", css)
+	}
+}
+
+func TestSourceMaps_WrittenByFlushToDisk(t *testing.T) {
+	am := newTestEnv(t, &assetmin.Config{SourceMaps: true, WriteSourceMaps: true}).withModules(sourceMapModules...).AssetsHandler
+
+	if err := am.FlushToDisk(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{am.GetMainCssPath() + ".map", am.GetMainJsPath() + ".map"} {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("expected source map on disk: %v", err)
+		}
+		var m sourceMap
+		if err := json.Unmarshal(raw, &m); err != nil {
+			t.Fatalf("invalid source map %s: %v", path, err)
+		}
+		if len(m.Sources) != 2 {
+			t.Errorf("%s sources = %v, want both modules", path, m.Sources)
+		}
+	}
+}
+
+func TestSourceMaps_PrunedModuleMapsToItsStart(t *testing.T) {
+	env := newTestEnv(t, &assetmin.Config{SourceMaps: true, WriteSourceMaps: true, PruneCSS: true})
+	am := env.AssetsHandler
+	am.Change(assetmin.MinifyOptionOff)
+	env.withModules(&assetmin.SSRAssets{
+		ModuleName: "example.com/card",
+		CSS:        ".kept{color:red}\n.unused{color:blue}\n.also{color:green}",
+		HTML:       `<div class="kept also"></div>`,
+	})
+
+	bundle, err := fs.ReadFile(am.FS(), "style.css")
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := fs.ReadFile(am.FS(), "style.css.map")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m sourceMap
+	if err := json.Unmarshal(raw, &m); err != nil {
+		t.Fatalf("invalid source map %s: %v", raw, err)
+	}
+	if strings.Contains(string(bundle), ".unused") {
+		t.Fatalf("rule not pruned:\n%s", bundle)
+	}
+	for i, line := range strings.Split(string(bundle), "\n") {
+		col := strings.Index(line, ".also")
+		if col < 0 {
+			continue
+		}
+		// Pruning shifted .also up a line: it must not map to .unused.
+		if source, orig := m.originAt(t, i, col); source != "example.com/card" || orig != 0 {
+			t.Errorf("line %d (%q) maps to %s:%d, want the module start", i, line, source, orig)
+		}
+	}
+}
+
+func TestSourceMaps_BundlesMatchWithMapsOff(t *testing.T) {
+	modules := append(append([]*assetmin.SSRAssets{}, sourceMapModules...), &assetmin.SSRAssets{
+		ModuleName: "example.com/gamma",
+		CSS:        ".gamma{color:red}\n.unused{color:blue}",
+		HTML:       `<div class="alpha beta gamma"></div>`,
+		JS:         []*js.Script{{Content: "var gamma = 1;\nconsole.log(gamma)"}},
+	})
+	for _, cfg := range []assetmin.Config{{}, {PruneCSS: true}} {
+		plain := newTestEnv(t, &cfg).withModules(modules...).AssetsHandler
+		mappedCfg := cfg
+		mappedCfg.SourceMaps = true
+		mapped := newTestEnv(t, &mappedCfg).withModules(modules...).AssetsHandler
+
+		for _, name := range []string{"style.css", "script.js"} {
+			want, got := outputString(t, plain, name), outputString(t, mapped, name)
+			if got != want {
+				t.Errorf("PruneCSS=%v: %s differs with source maps on:\n got %q\nwant %q", cfg.PruneCSS, name, got, want)
+			}
+		}
+	}
+}
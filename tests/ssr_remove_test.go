@@ -0,0 +1,211 @@
+//go:build !wasm
+
+package assetmin_test
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tinywasm/assetmin"
+	"github.com/tinywasm/js"
+)
+
+func outputString(t *testing.T, am *assetmin.AssetMin, name string) string {
+	t.Helper()
+	content, err := fs.ReadFile(am.FS(), name)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	return string(content)
+}
+
+func TestRemoveSSRModule_RetractsEveryContribution(t *testing.T) {
+	outDir := t.TempDir()
+	am := newTestEnv(t, &assetmin.Config{OutputDir: outDir}).loadSSR(&testExtractor{all: []*assetmin.SSRAssets{
+		{ModuleName: "github.com/acme/ui", CSS: ".ui{color:red}", HTML: "<nav>ui</nav>",
+			JS: []*js.Script{{Content: "uiInit()"}, {Name: "sw.js", Content: "self.ui=1"}}, Icons: iconSprite("icon-ui")},
+		{ModuleName: "github.com/acme/kept", CSS: ".kept{color:blue}", JS: []*js.Script{{Content: "keptInit()"}}},
+	}}).AssetsHandler
+	if err := am.FlushToDisk(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := am.RemoveSSRModule("github.com/acme/ui"); err != nil {
+		t.Fatal(err)
+	}
+
+	css := outputString(t, am, "style.css")
+	if strings.Contains(css, ".ui") || !strings.Contains(css, ".kept") {
+		t.Errorf("style.css = %q", css)
+	}
+	if script := outputString(t, am, "script.js"); strings.Contains(script, "uiInit") || !strings.Contains(script, "keptInit") {
+		t.Errorf("script.js = %q", script)
+	}
+	if html := outputString(t, am, "index.html"); strings.Contains(html, "<nav>ui</nav>") || strings.Contains(html, "icon-ui") {
+		t.Errorf("index.html still holds the module: %q", html)
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "sw.js")); !os.IsNotExist(err) {
+		t.Errorf("standalone sw.js left on disk: %v", err)
+	}
+	for _, m := range am.Modules() {
+		if m.Name == "github.com/acme/ui" {
+			t.Error("removed module still in Modules()")
+		}
+	}
+
+	if err := am.RemoveSSRModule("github.com/acme/unknown"); err != nil {
+		t.Errorf("removing an unknown module: %v", err)
+	}
+}
+
+func TestRemoveSSRModule_RootFallsBackToFrameworkTheme(t *testing.T) {
+	am := newTestEnv(t, &assetmin.Config{}).loadSSR(&testExtractor{all: []*assetmin.SSRAssets{
+		{ModuleName: "example.com/app", IsRoot: true, RootCSS: ":root{--c:red}"},
+		{ModuleName: "github.com/tinywasm/css", IsFramework: true, RootCSS: ":root{--c:blue}"},
+	}}).AssetsHandler
+	if err := am.RemoveSSRModule("example.com/app"); err != nil {
+		t.Fatal(err)
+	}
+	css := outputString(t, am, "style.css")
+	if strings.Contains(css, "red") || !strings.Contains(css, "blue") {
+		t.Errorf("style.css = %q", css)
+	}
+}
+
+func TestSSRWatcher_RemovedSourcesRetractTheModule(t *testing.T) {
+	dir := t.TempDir()
+	ui := filepath.Join(dir, "ui")
+	os.MkdirAll(ui, 0755)
+	os.WriteFile(filepath.Join(ui, "css.go"), []byte("package ui"), 0644)
+	ex := &testExtractor{byDir: map[string]*assetmin.SSRAssets{ui: {ModuleName: "github.com/acme/ui", CSS: ".ui{color:red}"}}}
+	am := newTestEnv(t, &assetmin.Config{}).loadSSR(ex).AssetsHandler
+	w := am.NewSSRFileWatcher(nil)
+	if err := w.NewFileEvent("css.go", ".go", filepath.Join(ui, "css.go"), "write"); err != nil {
+		t.Fatal(err)
+	}
+	if css := outputString(t, am, "style.css"); !strings.Contains(css, ".ui") {
+		t.Fatalf("module not loaded: %q", css)
+	}
+
+	os.Remove(filepath.Join(ui, "css.go"))
+	if err := w.NewFileEvent("css.go", ".go", filepath.Join(ui, "css.go"), "remove"); err != nil {
+		t.Fatal(err)
+	}
+	if css := outputString(t, am, "style.css"); strings.Contains(css, ".ui") {
+		t.Errorf("removed module still in style.css: %q", css)
+	}
+}
+
+func TestSSRWatcher_RemovedDirectoryRetractsItsModules(t *testing.T) {
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/app\n\ngo 1.22\n"), 0644)
+	// Loaded by ExtractAll: no directory recorded, matched by import path.
+	am := newTestEnv(t, &assetmin.Config{RootDir: root}).loadSSR(&testExtractor{all: []*assetmin.SSRAssets{
+		{ModuleName: "example.com/app/components/button", CSS: ".button{color:red}"},
+		{ModuleName: "example.com/app/components/card", CSS: ".card{color:red}"},
+		{ModuleName: "example.com/app/pages", CSS: ".pages{color:red}"},
+	}}).AssetsHandler
+
+	w := am.NewSSRFileWatcher(nil)
+	if err := w.NewFileEvent("components", "", filepath.Join(root, "components"), "remove"); err != nil {
+		t.Fatal(err)
+	}
+	css := outputString(t, am, "style.css")
+	if strings.Contains(css, ".button") || strings.Contains(css, ".card") || !strings.Contains(css, ".pages") {
+		t.Errorf("style.css = %q", css)
+	}
+}
+
+func TestSSRWatcher_RenamedAwayRetractsLikeARemoval(t *testing.T) {
+	dir := t.TempDir()
+	ui, card := filepath.Join(dir, "ui"), filepath.Join(dir, "card")
+	for _, d := range []string{ui, card} {
+		os.MkdirAll(d, 0755)
+		os.WriteFile(filepath.Join(d, "css.go"), []byte("package x"), 0644)
+	}
+	os.WriteFile(filepath.Join(ui, "LICENSE"), []byte("MIT"), 0644)
+	ex := &testExtractor{byDir: map[string]*assetmin.SSRAssets{
+		ui:   {ModuleName: "github.com/acme/ui", CSS: ".ui{color:red}"},
+		card: {ModuleName: "github.com/acme/card", CSS: ".card{color:red}"},
+	}}
+	am := newTestEnv(t, &assetmin.Config{}).loadSSR(ex).AssetsHandler
+	w := am.NewSSRFileWatcher(nil)
+	for _, d := range []string{ui, card} {
+		if err := w.NewFileEvent("css.go", ".go", filepath.Join(d, "css.go"), "write"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// An extensionless file is not a module directory.
+	os.Remove(filepath.Join(ui, "LICENSE"))
+	if err := w.NewFileEvent("LICENSE", "", filepath.Join(ui, "LICENSE"), "remove"); err != nil {
+		t.Fatal(err)
+	}
+	if css := outputString(t, am, "style.css"); !strings.Contains(css, ".ui") {
+		t.Errorf("removing a plain file dropped its module: %q", css)
+	}
+
+	os.Rename(filepath.Join(ui, "css.go"), filepath.Join(ui, "css.go.bak"))
+	if err := w.NewFileEvent("css.go", ".go", filepath.Join(ui, "css.go"), "rename"); err != nil {
+		t.Fatal(err)
+	}
+	os.Rename(card, filepath.Join(dir, "card-old"))
+	if err := w.NewFileEvent("card", "", card, "rename"); err != nil {
+		t.Fatal(err)
+	}
+	if css := outputString(t, am, "style.css"); strings.Contains(css, ".ui") || strings.Contains(css, ".card") {
+		t.Errorf("modules renamed away still in style.css: %q", css)
+	}
+}
+
+func TestSSRWatcher_RemovedSourceKeepsNestedModules(t *testing.T) {
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/app\n\ngo 1.22\n"), 0644)
+	ui, button := filepath.Join(root, "ui"), filepath.Join(root, "ui", "button")
+	for _, d := range []string{ui, button} {
+		os.MkdirAll(d, 0755)
+		os.WriteFile(filepath.Join(d, "css.go"), []byte("package x"), 0644)
+	}
+	ex := &testExtractor{
+		// Loaded by ExtractAll: no directory recorded, matched by import path.
+		all: []*assetmin.SSRAssets{
+			{ModuleName: "example.com/app", IsRoot: true, CSS: ".app{color:red}"},
+			{ModuleName: "example.com/app/pages", CSS: ".pages{color:red}"},
+		},
+		byDir: map[string]*assetmin.SSRAssets{
+			ui:     {ModuleName: "example.com/app/ui", CSS: ".ui{color:red}"},
+			button: {ModuleName: "example.com/app/ui/button", CSS: ".button{color:red}"},
+		},
+	}
+	am := newTestEnv(t, &assetmin.Config{RootDir: root}).loadSSR(ex).AssetsHandler
+	w := am.NewSSRFileWatcher(nil)
+	for _, d := range []string{ui, button} {
+		if err := w.NewFileEvent("css.go", ".go", filepath.Join(d, "css.go"), "write"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, d := range []string{root, ui} {
+		os.Remove(filepath.Join(d, "css.go"))
+		if err := w.NewFileEvent("css.go", ".go", filepath.Join(d, "css.go"), "remove"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	css := outputString(t, am, "style.css")
+	if strings.Contains(css, ".app") || strings.Contains(css, ".ui{") {
+		t.Errorf("modules whose source was removed still in style.css: %q", css)
+	}
+	if !strings.Contains(css, ".pages") || !strings.Contains(css, ".button") {
+		t.Errorf("nested modules dropped with their parent: %q", css)
+	}
+	var names []string
+	for _, m := range am.Modules() {
+		names = append(names, m.Name)
+	}
+	if strings.Join(names, ",") != "example.com/app/pages,example.com/app/ui/button" {
+		t.Errorf("Modules() = %v", names)
+	}
+}
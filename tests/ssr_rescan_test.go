@@ -0,0 +1,135 @@
+//go:build !wasm
+
+package assetmin_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tinywasm/assetmin"
+)
+
+// swapExtractor returns the module list last set, as a dependency change would.
+type swapExtractor struct {
+	mu    sync.Mutex
+	all   []*assetmin.SSRAssets
+	calls int
+}
+
+func (s *swapExtractor) set(all ...*assetmin.SSRAssets) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.all = all
+}
+
+func (s *swapExtractor) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls
+}
+
+func (s *swapExtractor) ExtractModule(moduleDir string) (*assetmin.SSRAssets, error) {
+	return nil, nil
+}
+
+func (s *swapExtractor) ExtractAll() ([]*assetmin.SSRAssets, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	return s.all, nil
+}
+
+func TestRescan_GoModChangeReconcilesModules(t *testing.T) {
+	ex := &swapExtractor{}
+	ex.set(
+		&assetmin.SSRAssets{ModuleName: "example.com/app", CSS: ".app{color:red}", IsRoot: true},
+		&assetmin.SSRAssets{ModuleName: "github.com/acme/old", CSS: ".old{color:blue}", Icons: iconSprite("icon-old")},
+	)
+	am := newTestEnv(t, &assetmin.Config{}).loadSSR(ex).AssetsHandler
+	if !am.ContainsCSS(".old") {
+		t.Fatal("precondition: old module not served")
+	}
+
+	// go get swaps the dependency: the old module is gone, a new one ships.
+	ex.set(
+		&assetmin.SSRAssets{ModuleName: "example.com/app", CSS: ".app{color:red}", IsRoot: true},
+		&assetmin.SSRAssets{ModuleName: "github.com/acme/new", CSS: ".new{color:green}"},
+	)
+	reloads := 0
+	w := am.NewSSRFileWatcher(func() error { reloads++; return nil })
+	root := t.TempDir()
+	for _, name := range []string{"go.mod", "go.sum"} {
+		if err := w.NewFileEvent(name, filepath.Ext(name), filepath.Join(root, name), "write"); err != nil {
+			t.Fatalf("NewFileEvent(%s): %v", name, err)
+		}
+	}
+	waitForSSRLoad(t, am, 5*time.Second, "rescan did not finish")
+
+	if got := ex.count(); got != 2 {
+		t.Errorf("ExtractAll calls = %d, want 2 (go.mod and go.sum debounced into one rescan)", got)
+	}
+	if am.ContainsCSS(".old") || am.ContainsSVG("icon-old") {
+		t.Error("vanished module still served")
+	}
+	if !am.ContainsCSS(".new") || !am.ContainsCSS(".app") {
+		t.Error("rescan did not load the current modules")
+	}
+	var names []string
+	for _, m := range am.Modules() {
+		names = append(names, m.Name)
+	}
+	if len(names) != 2 || names[0] != "example.com/app" || names[1] != "github.com/acme/new" {
+		t.Errorf("Modules() = %v", names)
+	}
+	if reloads != 1 {
+		t.Errorf("browser reloads = %d, want 1", reloads)
+	}
+}
+
+func TestRescan_FailedExtractionKeepsModules(t *testing.T) {
+	ex := &retryExtractor{failCount: 1, failResult: errors.New("go list: connection refused"), successRes: []*assetmin.SSRAssets{
+		{ModuleName: "github.com/acme/ui", CSS: ".ui{color:red}"},
+	}}
+	am := newTestEnv(t, &assetmin.Config{SSRRetry: fastRetry}).loadSSR(ex).AssetsHandler
+	if !am.ContainsCSS(".ui") {
+		t.Fatal("precondition: module not served")
+	}
+
+	// A rescan whose extraction fails must not be read as "every module vanished".
+	ex.failCount, ex.calls = 99, 0
+	w := am.NewSSRFileWatcher(nil)
+	if err := w.NewFileEvent("go.work", ".work", filepath.Join(t.TempDir(), "go.work"), "write"); err != nil {
+		t.Fatalf("NewFileEvent: %v", err)
+	}
+	waitForSSRLoad(t, am, 5*time.Second, "rescan did not finish")
+	if am.SSRLoadError() == nil {
+		t.Fatal("precondition: rescan should have failed")
+	}
+	if !am.ContainsCSS(".ui") || len(am.Modules()) != 1 {
+		t.Error("failed rescan dropped loaded modules")
+	}
+}
+
+func TestRescan_CloseCancelsPendingRescan(t *testing.T) {
+	ex := &swapExtractor{}
+	ex.set(&assetmin.SSRAssets{ModuleName: "github.com/acme/ui", CSS: ".ui{color:red}"})
+	am := newTestEnv(t, &assetmin.Config{}).loadSSR(ex).AssetsHandler
+
+	w := am.NewSSRFileWatcher(nil)
+	if err := w.NewFileEvent("go.mod", ".mod", filepath.Join(t.TempDir(), "go.mod"), "write"); err != nil {
+		t.Fatalf("NewFileEvent: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := am.Close(ctx); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	time.Sleep(400 * time.Millisecond)
+	if got := ex.count(); got != 1 {
+		t.Errorf("ExtractAll calls = %d, want 1 (the pending rescan must not run after Close)", got)
+	}
+}
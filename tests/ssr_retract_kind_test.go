@@ -0,0 +1,88 @@
+//go:build !wasm
+
+package assetmin_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tinywasm/assetmin"
+	"github.com/tinywasm/js"
+)
+
+func TestReload_ClearsKindsTheModuleNoLongerProvides(t *testing.T) {
+	dir := t.TempDir()
+	full := &assetmin.SSRAssets{
+		ModuleName: "github.com/acme/ui",
+		CSS:        ".ui{color:red}",
+		JS:         []*js.Script{{Content: "uiInit()"}},
+		HTML:       "<nav>ui</nav>",
+		Icons:      iconSprite("icon-ui"),
+	}
+	ex := &testExtractor{byDir: map[string]*assetmin.SSRAssets{dir: full}}
+	am := newTestEnv(t, &assetmin.Config{}).loadSSR(ex).AssetsHandler
+	if err := am.ReloadSSRModule(dir); err != nil {
+		t.Fatal(err)
+	}
+	if css := outputString(t, am, "style.css"); !strings.Contains(css, ".ui") {
+		t.Fatalf("module not loaded: %q", css)
+	}
+
+	cases := []struct {
+		name   string
+		drop   func(a *assetmin.SSRAssets)
+		output string
+		gone   string
+	}{
+		{"css.go deleted", func(a *assetmin.SSRAssets) { a.CSS = "" }, "style.css", ".ui"},
+		{"js.go deleted", func(a *assetmin.SSRAssets) { a.JS = nil }, "script.js", "uiInit"},
+		{"html.go deleted", func(a *assetmin.SSRAssets) { a.HTML = "" }, "index.html", "<nav>ui</nav>"},
+		{"svg.go deleted", func(a *assetmin.SSRAssets) { a.Icons = nil }, "icons.svg", "icon-ui"},
+		{"svg.go deleted (inline sprite)", func(a *assetmin.SSRAssets) { a.Icons = nil }, "index.html", "icon-ui"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			reduced := *full
+			tc.drop(&reduced)
+			ex.byDir[dir] = &reduced
+			t.Cleanup(func() {
+				ex.byDir[dir] = full
+				am.ReloadSSRModule(dir)
+			})
+
+			if err := am.ReloadSSRModule(dir); err != nil {
+				t.Fatal(err)
+			}
+			if out := outputString(t, am, tc.output); strings.Contains(out, tc.gone) {
+				t.Errorf("%s still holds %q: %q", tc.output, tc.gone, out)
+			}
+		})
+	}
+}
+
+func TestReload_EmptiedModuleIsRewrittenOnDisk(t *testing.T) {
+	dir := t.TempDir()
+	outDir := t.TempDir()
+	ex := &testExtractor{byDir: map[string]*assetmin.SSRAssets{dir: {ModuleName: "github.com/acme/ui", CSS: ".ui{color:red}"}}}
+	am := newTestEnv(t, &assetmin.Config{OutputDir: outDir}).loadSSR(ex).AssetsHandler
+	if err := am.ReloadSSRModule(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := am.FlushToDisk(); err != nil {
+		t.Fatal(err)
+	}
+
+	ex.byDir[dir] = &assetmin.SSRAssets{ModuleName: "github.com/acme/ui"}
+	if err := am.ReloadSSRModule(dir); err != nil {
+		t.Fatal(err)
+	}
+	css, err := os.ReadFile(filepath.Join(outDir, "style.css"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(css), ".ui") {
+		t.Errorf("style.css on disk still holds the deleted CSS: %q", css)
+	}
+}
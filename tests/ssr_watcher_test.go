@@ -5,6 +5,7 @@ package assetmin_test
 import (
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
@@ -37,6 +38,7 @@ func (f *fakeExtractor) ExtractAll() ([]*assetmin.SSRAssets, error) {
 
 type fakeImageProcessor struct {
 	reloaded []string
+	outputs  []string // UnobservedFiles
 }
 
 func (f *fakeImageProcessor) LoadImages() error { return nil }
@@ -44,7 +46,7 @@ func (f *fakeImageProcessor) ReloadModule(moduleDir string) error {
 	f.reloaded = append(f.reloaded, moduleDir)
 	return nil
 }
-func (f *fakeImageProcessor) UnobservedFiles() []string { return nil }
+func (f *fakeImageProcessor) UnobservedFiles() []string { return f.outputs }
 
 // ssrWatcherEnv builds an AssetMin in SSR mode with a module dir on disk whose
 // css.go carries the given marker.
@@ -95,8 +97,8 @@ func TestSSRWatcher_Contract(t *testing.T) {
 		t.Errorf("MainInputFileRelativePath() = %q, want \"go.mod\" (a non-.go main input is what bypasses devwatch's depfind gate)", got)
 	}
 	exts := w.SupportedExtensions()
-	if len(exts) != 1 || exts[0] != ".go" {
-		t.Errorf("SupportedExtensions() = %v, want [\".go\"]", exts)
+	if want := []string{".go", ".mod", ".sum", ".work"}; !slices.Equal(exts, want) {
+		t.Errorf("SupportedExtensions() = %v, want %v", exts, want)
 	}
 }
 
@@ -0,0 +1,136 @@
+//go:build !wasm
+
+package assetmin_test
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tinywasm/assetmin"
+)
+
+func flushWith(t *testing.T, outDir string, css string) {
+	t.Helper()
+	am := newTestEnv(t, &assetmin.Config{RootDir: t.TempDir(), OutputDir: outDir}).
+		loadSSR(&testExtractor{all: []*assetmin.SSRAssets{{ModuleName: "app", CSS: css, HTML: "<p>app</p>"}}}).
+		AssetsHandler
+	if err := am.FlushToDisk(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWarmStart_ServesPreviousFlushUntilLoadCompletes(t *testing.T) {
+	outDir := t.TempDir()
+	flushWith(t, outDir, ".old{color:red}")
+
+	gate := &testExtractor{release: make(chan struct{}), all: []*assetmin.SSRAssets{{ModuleName: "app", CSS: ".new{color:blue}", HTML: "<p>app</p>"}}}
+	am := assetmin.NewAssetMin(&assetmin.Config{RootDir: t.TempDir(), OutputDir: outDir, WarmStart: true})
+	am.SetSSRExtractor(gate)
+	am.LoadSSRModules()
+
+	css, err := am.GetMinifiedCSS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(css), ".old{") {
+		t.Fatalf("before the load, style.css = %q; want the flushed one", css)
+	}
+	html, err := fs.ReadFile(am.FS(), "index.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(html), "<p>app</p>") {
+		t.Errorf("before the load, index.html lacks the module HTML: %q", html)
+	}
+	if got, want := am.WarmAssets(), []string{"favicon.svg", "icons.svg", "index.html", "script.js", "style.css"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("WarmAssets() = %v, want %v", got, want)
+	}
+
+	// A module change during the load does not leak a partial bundle.
+	if err := am.UpdateSSRModule("other", ".partial{color:green}", nil, "", nil); err != nil {
+		t.Fatal(err)
+	}
+	css, _ = am.GetMinifiedCSS()
+	if strings.Contains(string(css), ".partial{") {
+		t.Errorf("held style.css changed before the load completed: %q", css)
+	}
+
+	close(gate.release)
+	waitForSSRLoad(t, am, 5*time.Second, "load did not finish")
+	css, _ = am.GetMinifiedCSS()
+	if strings.Contains(string(css), ".old{") || !strings.Contains(string(css), ".new{") || !strings.Contains(string(css), ".partial{") {
+		t.Errorf("after the load, style.css = %q", css)
+	}
+	if got := am.WarmAssets(); len(got) != 0 {
+		t.Errorf("WarmAssets() after the load = %v", got)
+	}
+}
+
+func TestWarmStart_SkipsFilesEditedSinceTheManifest(t *testing.T) {
+	outDir := t.TempDir()
+	flushWith(t, outDir, ".old{color:red}")
+	if err := os.WriteFile(filepath.Join(outDir, "style.css"), []byte(".edited{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	gate := &testExtractor{release: make(chan struct{})}
+	defer close(gate.release)
+	am := assetmin.NewAssetMin(&assetmin.Config{RootDir: t.TempDir(), OutputDir: outDir, WarmStart: true})
+	am.SetSSRExtractor(gate)
+	am.LoadSSRModules()
+
+	css, _ := am.GetMinifiedCSS()
+	if strings.Contains(string(css), ".edited") {
+		t.Errorf("style.css not matching the manifest hash was served: %q", css)
+	}
+	for _, name := range am.WarmAssets() {
+		if name == "style.css" {
+			t.Error("style.css reported as warm")
+		}
+	}
+}
+
+func TestWarmStart_Disabled(t *testing.T) {
+	outDir := t.TempDir()
+	flushWith(t, outDir, ".old{color:red}")
+
+	am := assetmin.NewAssetMin(&assetmin.Config{RootDir: t.TempDir(), OutputDir: outDir})
+	css, _ := am.GetMinifiedCSS()
+	if strings.Contains(string(css), ".old{") {
+		t.Errorf("previous flush served without WarmStart: %q", css)
+	}
+	if got := am.WarmAssets(); len(got) != 0 {
+		t.Errorf("WarmAssets() = %v", got)
+	}
+}
+
+func TestWarmStart_ReleasedByFirstFileEventWithoutExtractor(t *testing.T) {
+	outDir := t.TempDir()
+	flushWith(t, outDir, ".old{color:red}")
+
+	root := t.TempDir()
+	am := assetmin.NewAssetMin(&assetmin.Config{RootDir: root, OutputDir: outDir, WarmStart: true})
+	if len(am.WarmAssets()) == 0 {
+		t.Fatal("nothing warm-started")
+	}
+
+	file := filepath.Join(root, "a.css")
+	if err := os.WriteFile(file, []byte(".a{color:blue}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := am.NewFileEvent("a.css", ".css", file, "create"); err != nil {
+		t.Fatal(err)
+	}
+	if got := am.WarmAssets(); len(got) != 0 {
+		t.Errorf("WarmAssets() after a file event = %v", got)
+	}
+	css, _ := am.GetMinifiedCSS()
+	if strings.Contains(string(css), ".old{") || !strings.Contains(string(css), ".a{") {
+		t.Errorf("style.css after a file event = %q", css)
+	}
+}
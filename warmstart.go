@@ -0,0 +1,70 @@
+package assetmin
+
+import (
+	"sort"
+	"time"
+)
+
+// warmStart seeds the main assets with the outputs of a previous flush found
+// in the OutputSink, so they are served before the first SSR load completes.
+// When an asset-manifest.json is present, a file is only used if its content
+// still matches the manifest hash; otherwise every main output found is used.
+func (c *AssetMin) warmStart() {
+	var entries map[string]ManifestEntry // by path; nil without a manifest
+	if content, err := c.OutputSink.ReadFile(manifestFileName); err == nil {
+		if m, err := parseManifest(content); err == nil {
+			entries = make(map[string]ManifestEntry, len(m.Assets))
+			for _, e := range m.Assets {
+				entries[e.Path] = e
+			}
+		}
+	}
+
+	for _, a := range c.allAssets {
+		name := c.sinkName(a.outputPath)
+		content, err := c.OutputSink.ReadFile(name)
+		if err != nil {
+			continue
+		}
+		if entries != nil {
+			if e, ok := entries[name]; !ok || e.Hash != contentHash(content) {
+				continue // edited or left over since the flush that wrote the manifest
+			}
+		}
+		modTime := time.Now()
+		if info, err := c.OutputSink.Stat(name); err == nil {
+			modTime = info.ModTime()
+		}
+		a.hold(content, modTime)
+	}
+}
+
+// releaseWarmStart stops serving warm-started outputs; the next build of each
+// uses the loaded content. It reports whether any output was held.
+// It assumes the caller holds c.mu.
+func (c *AssetMin) releaseWarmStart() bool {
+	released := false
+	for _, a := range c.allAssets {
+		if a.release() {
+			released = true
+		}
+	}
+	return released
+}
+
+// WarmAssets returns the names of the outputs still served from a previous
+// flush (see Config.WarmStart), sorted. It is empty once the first SSR load
+// has completed or, without an SSR extractor, once the first file event was
+// applied.
+func (c *AssetMin) WarmAssets() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var names []string
+	for _, a := range c.allAssets {
+		if a.isHeld() {
+			names = append(names, a.fileOutputName)
+		}
+	}
+	sort.Strings(names)
+	return names
+}